@@ -51,38 +51,73 @@ esac
 rm "$TEMP_FILE"
 chmod +x "${HOME}/tools/griffin"
 
-# Check what needs to be added to .zshrc
-ZSHRC="${HOME}/.zshrc"
+# Detect the shell to integrate with, and its startup file
+SHELL_NAME=$(basename "${SHELL:-}")
+case "$SHELL_NAME" in
+  zsh)
+    RC_FILE="${HOME}/.zshrc"
+    PATH_MARKER='${HOME}/tools'
+    PATH_LINE='export PATH="${HOME}/tools:$PATH"'
+    INTEGRATION_LINE='source <(griffin shell-integration --shell zsh)'
+    ;;
+  bash)
+    RC_FILE="${HOME}/.bashrc"
+    PATH_MARKER='${HOME}/tools'
+    PATH_LINE='export PATH="${HOME}/tools:$PATH"'
+    INTEGRATION_LINE='eval "$(griffin shell-integration --shell bash)"'
+    ;;
+  fish)
+    RC_FILE="${HOME}/.config/fish/config.fish"
+    PATH_MARKER='$HOME/tools'
+    PATH_LINE='fish_add_path "$HOME/tools"'
+    INTEGRATION_LINE='griffin shell-integration --shell fish | source'
+    mkdir -p "$(dirname "$RC_FILE")"
+    ;;
+  *)
+    RC_FILE=""
+    ;;
+esac
+
+if [ -z "$RC_FILE" ]; then
+  echo "Could not set up the shell integration for '${SHELL:-unknown shell}' - add ${HOME}/tools to your PATH and see"
+  echo "'griffin shell-integration -h' (nushell and powershell are set up manually, as described in the README)."
+  xattr -d com.apple.quarantine "${HOME}/tools/griffin" 2>/dev/null || true
+  exit 0
+fi
+
+RC_NAME=$(basename "$RC_FILE")
+
+# Check what needs to be added to the startup file
 NEED_PATH=false
 NEED_INTEGRATION=false
 
-if [ ! -f "$ZSHRC" ] || ! grep -q '${HOME}/tools' "$ZSHRC"; then
+if [ ! -f "$RC_FILE" ] || ! grep -qF "$PATH_MARKER" "$RC_FILE"; then
   NEED_PATH=true
 fi
 
-if [ ! -f "$ZSHRC" ] || ! grep -q 'griffin shell-integration' "$ZSHRC"; then
+if [ ! -f "$RC_FILE" ] || ! grep -q 'griffin shell-integration' "$RC_FILE"; then
   NEED_INTEGRATION=true
 fi
 
 # If any changes are needed, back up the file first
 if [ "$NEED_PATH" = true ] || [ "$NEED_INTEGRATION" = true ]; then
-  if [ -f "$ZSHRC" ]; then
-    cp "$ZSHRC" "$ZSHRC.griffin-backup"
-    echo "Backed up .zshrc to .zshrc.griffin-backup"
+  if [ -f "$RC_FILE" ]; then
+    cp "$RC_FILE" "$RC_FILE.griffin-backup"
+    echo "Backed up ${RC_NAME} to ${RC_NAME}.griffin-backup"
   fi
   
   if [ "$NEED_PATH" = true ]; then
-    echo 'export PATH="${HOME}/tools:$PATH"' >> "$ZSHRC"
-    echo "Added ${HOME}/tools to PATH in .zshrc"
+    echo "$PATH_LINE" >> "$RC_FILE"
+    echo "Added ${HOME}/tools to PATH in ${RC_NAME}"
   fi
   
   if [ "$NEED_INTEGRATION" = true ]; then
-    echo 'source <(griffin shell-integration)' >> "$ZSHRC"
-    echo "Added griffin shell integration to .zshrc"
+    echo "$INTEGRATION_LINE" >> "$RC_FILE"
+    echo "Added griffin shell integration to ${RC_NAME}"
   fi
 fi
 
 # Remove quarantine attribute (macOS)
 xattr -d com.apple.quarantine "${HOME}/tools/griffin" 2>/dev/null || true
 
-echo "Installation complete! Run 'source ~/.zshrc' or restart your terminal."
+echo "Installation complete! Run 'source ${RC_FILE}' or restart your terminal."
@@ -3,18 +3,21 @@ package alfred
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 
 	projectIndex "ronkitay.com/griffin/pkg/projectindex"
 	repo "ronkitay.com/griffin/pkg/repoindex"
 )
 
-func ReposAsAlfred(matchingRepos []repo.RepoData) string {
+func ReposAsAlfred(matchingRepos []repo.RepoData) (string, error) {
 	var items []Item
 
 	for _, repo := range matchingRepos {
-		items = append(items, buildAlfredItemForRepo(repo))
+		item, err := buildAlfredItemForRepo(repo)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, item)
 	}
 
 	result := map[string][]Item{
@@ -23,26 +26,27 @@ func ReposAsAlfred(matchingRepos []repo.RepoData) string {
 
 	jsonData, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		fmt.Println("Error marshaling JSON:", err)
-		os.Exit(1)
+		return "", fmt.Errorf("error marshaling JSON: %w", err)
 	}
 
-	return string(jsonData)
+	return string(jsonData), nil
 }
 
-func buildAlfredItemForRepo(repo repo.RepoData) Item {
+func buildAlfredItemForRepo(repo repo.RepoData) (Item, error) {
 	repoFullPath := filepath.Join(repo.BaseDir, repo.FullName)
 	switch repo.Type {
 	case "dir":
-		return buildDirectoryLocation(repoFullPath, repo.FullName)
+		return buildDirectoryLocation(repoFullPath, repo.FullName), nil
 	case "archive":
-		return buildArchiveLocation(repoFullPath, repo.FullName, repo.Url)
+		return buildArchiveLocation(repoFullPath, repo.FullName, repo.Url), nil
+	case "local":
+		return buildLocalRepoLocation(repoFullPath, repo.FullName), nil
 	case "gitlab":
 		fallthrough
 	case "github":
-		return buildGitRepoLocation(repoFullPath, repo.FullName, repo.Url, repo.Type)
+		return buildGitRepoLocation(repoFullPath, repo.FullName, repo.Url, repo.Type), nil
 	default:
-		panic("Unsupported locationType: " + repo.Type)
+		return Item{}, fmt.Errorf("unsupported locationType: %s", repo.Type)
 	}
 }
 
@@ -79,6 +83,31 @@ func buildArchiveLocation(repoDir string, repoName string, url string) Item {
 	}
 }
 
+func buildLocalRepoLocation(repoDir string, repoName string) Item {
+	return Item{
+		Valid:    true,
+		UID:      repoName,
+		Title:    repoName,
+		Subtitle: "Open in TERMINAL (🖥️) : " + repoDir,
+		Arg:      repoDir,
+		Mods: map[string]Modifier{
+			"ctrl": {
+				Valid:    true,
+				Arg:      repoDir,
+				Subtitle: "Open in EDITOR (📝): " + repoDir,
+			},
+			"ctrl+shift": {
+				Valid:    true,
+				Arg:      repoDir,
+				Subtitle: "Open in ALTERNATIVE EDITOR (🛠️): " + repoDir,
+			},
+		},
+		Icon: Icon{
+			Path: "icons/dir.jpg",
+		},
+	}
+}
+
 func buildGitRepoLocation(repoDir string, repoName string, url string, locationType string) Item {
 	return Item{
 		Valid:    true,
@@ -109,7 +138,7 @@ func buildGitRepoLocation(repoDir string, repoName string, url string, locationT
 	}
 }
 
-func ProjectsAsAlfred(matchingProjects []projectIndex.ProjectData) string {
+func ProjectsAsAlfred(matchingProjects []projectIndex.ProjectData) (string, error) {
 	var items []Item
 
 	for _, project := range matchingProjects {
@@ -122,11 +151,10 @@ func ProjectsAsAlfred(matchingProjects []projectIndex.ProjectData) string {
 
 	jsonData, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		fmt.Println("Error marshaling JSON:", err)
-		os.Exit(1)
+		return "", fmt.Errorf("error marshaling JSON: %w", err)
 	}
 
-	return string(jsonData)
+	return string(jsonData), nil
 }
 
 func buildAlfredItemForProject(project projectIndex.ProjectData) Item {
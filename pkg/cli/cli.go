@@ -4,17 +4,25 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"ronkitay.com/griffin/pkg/configuration"
 	"ronkitay.com/griffin/pkg/finder"
-	"ronkitay.com/griffin/pkg/idelauncher"
-	"ronkitay.com/griffin/pkg/projectindex"
+	"ronkitay.com/griffin/pkg/griffin"
+	"ronkitay.com/griffin/pkg/matcher"
+	"ronkitay.com/griffin/pkg/picker"
 	"ronkitay.com/griffin/pkg/repoindex"
+	"ronkitay.com/griffin/pkg/rpc"
+	"ronkitay.com/griffin/pkg/server"
 	"ronkitay.com/griffin/pkg/shell"
 	"ronkitay.com/griffin/pkg/terminal"
+	"ronkitay.com/griffin/pkg/watcher"
 )
 
-type CommandHandler func(*Command, string)
+// CommandHandler runs a command. Errors are reported by Run, which maps them to exit codes.
+type CommandHandler func(*Command, string) error
 
 type Command struct {
 	name, description string
@@ -26,9 +34,15 @@ var COMMANDS = []Command{
 	{"build-repo-index", "Builds the repository index", runBuildRepoIndexCommand},
 	{"find-project", "Finds projects based on given filters", runFindProjectCommand},
 	{"build-project-index", "Builds the projects index", runBuildProjectIndexCommand},
+	{"pick", "Interactively picks a repository or project, with a preview", runPickCommand},
+	{"watch", "Keeps the repository and project indexes up to date (Linux only)", runWatchCommand},
+	{"serve", "Answers search queries over HTTP, keeping the indexes in memory", runServeCommand},
+	{"rpc", "Answers JSON-RPC requests on stdin/stdout, for editor plugins", runRpcCommand},
 	{"shell-integration", "Generates Shell Integration commands", runShellIntegrationCommand},
+	{"completion", "Generates tab-completion for griffin's commands, flags and repository names", runCompletionCommand},
 	{"configure", "Configure the tool", runConfigureCommand},
-	{"open-in-ide", "Opens a given path in the appropriate IDE", runInIDECommand},
+	{"open-in-ide", "Opens a given path, or file:line, in the appropriate IDE", runInIDECommand},
+	{"record", "Records that a path was selected, to rank it higher in future searches", runRecordCommand},
 }
 
 const COMMAND_NOT_SUPPORTED_ERROR_MESSAGE = terminal.BOLD_COLOR + terminal.RED_COLOR + "Command '" + terminal.WHITE_COLOR + "%s" + terminal.RED_COLOR + "' is not supported!" + terminal.RESET_COLORS + "\n"
@@ -47,7 +61,9 @@ func Run() {
 	} else {
 		command := matchCommand(commandName)
 		if command != nil {
-			command.handler(command, executableName)
+			if err := command.handler(command, executableName); err != nil {
+				exitWithError(err)
+			}
 		} else {
 			fmt.Fprintf(os.Stderr, COMMAND_NOT_SUPPORTED_ERROR_MESSAGE, commandName)
 			printToolHelp(executableName)
@@ -66,7 +82,7 @@ func printToolHelp(executableName string) {
 	for _, commandName := range COMMANDS {
 		printSingleCommandDescription(commandName.name, commandName.description)
 	}
-	os.Exit(255)
+	os.Exit(EXIT_USAGE)
 }
 
 func printSingleCommandDescription(commandName, commandHelp string) {
@@ -74,7 +90,7 @@ func printSingleCommandDescription(commandName, commandHelp string) {
 }
 
 func matchCommand(commandName string) *Command {
-	for _, potentialMatch := range COMMANDS {
+	for _, potentialMatch := range append(COMMANDS, HIDDEN_COMMANDS...) {
 		if potentialMatch.name == commandName {
 			return &potentialMatch
 		}
@@ -82,84 +98,401 @@ func matchCommand(commandName string) *Command {
 	return nil
 }
 
-func runFindRepoCommand(command *Command, executableName string) {
+// newClient returns the client of griffin commands, which report indexing warnings on stderr - stdout carries their
+// results (and the JSON-RPC frames of the rpc command)
+func newClient() (*griffin.Client, error) {
+	loadedConfiguration, err := configuration.LoadConfiguration()
+	if err != nil {
+		return nil, err
+	}
+	loadedConfiguration.Warnings = os.Stderr
+	return griffin.NewClient(loadedConfiguration), nil
+}
+
+func runFindRepoCommand(command *Command, executableName string) error {
 	var showFindRepoHelp bool
 	flag.BoolVar(&showFindRepoHelp, "h", false, "Show Help")
 	flag.BoolVar(&showFindRepoHelp, "help", false, "Show Help")
 
-	var alfredOutput bool
-	flag.BoolVar(&alfredOutput, "alfred", false, "Format output for Alfred")
+	var options finder.RepoSearchOptions
+	registerSearchFlags(&options.SearchOptions)
 
-	var noArchives bool
-	flag.BoolVar(&noArchives, "noarchive", false, "Filter out Archives")
+	flag.BoolVar(&options.NoArchives, "noarchive", false, "Filter out Archives")
+	flag.BoolVar(&options.NoDirs, "nodir", false, "Filter out Directories")
+	flag.BoolVar(&options.MatchRemotes, "match-remotes", false, "Match filters against remote names and URLs as well")
+	flag.BoolVar(&options.ShowRemotes, "show-remotes", false, "Print the remotes of each repository")
 
-	var noDirs bool
-	flag.BoolVar(&noDirs, "nodir", false, "Filter out Directories")
-
-	flag.CommandLine.Parse(os.Args[2:])
+	flagArgs, queryArgs := splitFieldFilters(os.Args[2:])
+	if err := parseFlags(flagArgs); err != nil {
+		return err
+	}
 
 	if showFindRepoHelp {
 		printCommandHelp(executableName, command.name, true)
-	} else {
-		positionalArgs := flag.Args()
+		return nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
 
-		finder.FindRepo(executableName, noArchives, noDirs, alfredOutput, positionalArgs)
+	positionalArgs := append(flag.Args(), queryArgs...)
+	return finder.FindRepo(client, options, positionalArgs)
+}
+
+func registerSearchFlags(options *finder.SearchOptions) {
+	flag.BoolVar(&options.AlfredOutput, "alfred", false, "Format output for Alfred")
+	flag.StringVar(&options.Format, "format", "", "Output format: "+strings.Join(finder.SUPPORTED_FORMATS, ", ")+" or a Go template such as '{{.FullName}}\\t{{.Url}}' (default paths)")
+	flag.BoolVar(&options.Regex, "regex", false, "Match filters as a regular expression instead of fuzzy matching")
+	flag.BoolVar(&options.ShowScores, "show-scores", false, "Print the match score of each result")
+	flag.BoolVar(&options.NoHistory, "no-history", false, "Do not favor frequently and recently selected results")
+}
+
+// splitFieldFilters separates field filters from the other arguments, so that negated filters
+// such as `-name:legacy` are not mistaken for flags
+func splitFieldFilters(args []string) ([]string, []string) {
+	var flagArgs, filterArgs []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") && matcher.IsFieldFilter(arg) {
+			filterArgs = append(filterArgs, arg)
+		} else {
+			flagArgs = append(flagArgs, arg)
+		}
 	}
+	return flagArgs, filterArgs
 }
 
-func runBuildRepoIndexCommand(command *Command, executableName string) {
-	if err := repoindex.BuildRepoIndex(); err != nil {
-		fmt.Printf("Error building repo index: %v\n", err)
-		return
+func runBuildRepoIndexCommand(command *Command, executableName string) error {
+	var showBuildRepoIndexHelp bool
+	flag.BoolVar(&showBuildRepoIndexHelp, "h", false, "Show Help")
+	flag.BoolVar(&showBuildRepoIndexHelp, "help", false, "Show Help")
+
+	var jobs int
+	flag.IntVar(&jobs, "jobs", repoindex.DefaultJobs(), "Number of repositories to process in parallel")
+
+	var incremental bool
+	flag.BoolVar(&incremental, "incremental", false, "Only re-scan directories and repositories that changed since the last build")
+
+	if err := parseFlags(os.Args[2:]); err != nil {
+		return err
+	}
+
+	if showBuildRepoIndexHelp {
+		printCommandHelp(executableName, command.name, false)
+		return nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.BuildRepoIndex(jobs, incremental); err != nil {
+		return fmt.Errorf("error building repo index: %w", err)
 	}
 	fmt.Println("Repository index built successfully")
+	return nil
 }
 
-func runFindProjectCommand(command *Command, executableName string) {
+func runFindProjectCommand(command *Command, executableName string) error {
 	var showFindRepoHelp bool
 	flag.BoolVar(&showFindRepoHelp, "h", false, "Show Help")
 	flag.BoolVar(&showFindRepoHelp, "help", false, "Show Help")
 
-	var alfredOutput bool
-	flag.BoolVar(&alfredOutput, "alfred", false, "Format output for Alfred")
+	var options finder.SearchOptions
+	registerSearchFlags(&options)
 
-	flag.CommandLine.Parse(os.Args[2:])
+	flagArgs, queryArgs := splitFieldFilters(os.Args[2:])
+	if err := parseFlags(flagArgs); err != nil {
+		return err
+	}
 
 	if showFindRepoHelp {
 		printCommandHelp(executableName, command.name, true)
-	} else {
-		positionalArgs := flag.Args()
+		return nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	positionalArgs := append(flag.Args(), queryArgs...)
+	return finder.FindProjects(client, options, positionalArgs)
+}
+
+func runBuildProjectIndexCommand(command *Command, executableName string) error {
+	var showBuildProjectIndexHelp bool
+	flag.BoolVar(&showBuildProjectIndexHelp, "h", false, "Show Help")
+	flag.BoolVar(&showBuildProjectIndexHelp, "help", false, "Show Help")
+
+	if err := parseFlags(os.Args[2:]); err != nil {
+		return err
+	}
+
+	if showBuildProjectIndexHelp {
+		printCommandHelp(executableName, command.name, false)
+		return nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.BuildProjectIndex(); err != nil {
+		return fmt.Errorf("error building project index: %w", err)
+	}
+	return nil
+}
+
+func runPickCommand(command *Command, executableName string) error {
+	var showPickHelp bool
+	flag.BoolVar(&showPickHelp, "h", false, "Show Help")
+	flag.BoolVar(&showPickHelp, "help", false, "Show Help")
+
+	var pickProjects, openInIDE bool
+	flag.BoolVar(&pickProjects, "projects", false, "Pick a project instead of a repository")
+	flag.BoolVar(&openInIDE, "open-in-ide", false, "Open the selected item in its IDE on Enter, instead of printing its path")
+
+	var options picker.Options
+	flag.BoolVar(&options.SelectSingle, "select-1", false, "Select the only match of the query without showing the picker")
+	flag.StringVar(&options.PreviewCommand, "preview", "", "Shell command previewing the highlighted item, in which {} is its path (default: its git details and directory tree)")
+
+	var searchOptions griffin.RepoSearchOptions
+	flag.BoolVar(&searchOptions.Regex, "regex", false, "Match filters as a regular expression instead of fuzzy matching")
+	flag.BoolVar(&searchOptions.NoHistory, "no-history", false, "Do not favor frequently and recently selected results")
+	flag.BoolVar(&searchOptions.NoArchives, "noarchive", false, "Filter out Archives")
+	flag.BoolVar(&searchOptions.NoDirs, "nodir", false, "Filter out Directories")
+	flag.BoolVar(&searchOptions.MatchRemotes, "match-remotes", false, "Match filters against remote names and URLs as well")
+
+	flagArgs, queryArgs := splitFieldFilters(os.Args[2:])
+	if err := parseFlags(flagArgs); err != nil {
+		return err
+	}
+
+	if showPickHelp {
+		printCommandHelp(executableName, command.name, true)
+		return nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	source := picker.RepoSource(client, searchOptions)
+	if pickProjects {
+		source = picker.ProjectSource(client, searchOptions.SearchOptions)
+	}
+	if openInIDE {
+		options.EnterAction = picker.ACTION_OPEN_IDE
+	}
+	options.Query = append(flag.Args(), queryArgs...)
+
+	selection, err := picker.Pick(source, options)
+	if err != nil {
+		return err
+	}
+	return runPickedAction(client, selection)
+}
+
+// runPickedAction records the selection and acts on it. The path to cd into is printed, for the shell integration.
+func runPickedAction(client *griffin.Client, selection picker.Selection) error {
+	path := selection.Item.Path
+
+	// Like `griffin record` in the shell integration, failing to save the history does not fail the selection
+	client.Record(path)
+
+	switch selection.Action {
+	case picker.ACTION_OPEN_IDE:
+		return client.OpenInIDE(path)
+	case picker.ACTION_OPEN_ALTERNATIVE_IDE:
+		return client.OpenInAlternativeIDE(path)
+	case picker.ACTION_OPEN_WEB:
+		return client.OpenInBrowser(path)
+	default:
+		fmt.Println(path)
+		return nil
+	}
+}
+
+func runWatchCommand(command *Command, executableName string) error {
+	var showWatchHelp bool
+	flag.BoolVar(&showWatchHelp, "h", false, "Show Help")
+	flag.BoolVar(&showWatchHelp, "help", false, "Show Help")
+
+	var jobs int
+	flag.IntVar(&jobs, "jobs", repoindex.DefaultJobs(), "Number of repositories to process in parallel")
+
+	if err := parseFlags(os.Args[2:]); err != nil {
+		return err
+	}
+
+	if showWatchHelp {
+		printCommandHelp(executableName, command.name, false)
+		return nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Watch(client.Configuration(), jobs); err != nil {
+		return fmt.Errorf("error watching repositories: %w", err)
+	}
+	return nil
+}
+
+func runServeCommand(command *Command, executableName string) error {
+	var showServeHelp bool
+	flag.BoolVar(&showServeHelp, "h", false, "Show Help")
+	flag.BoolVar(&showServeHelp, "help", false, "Show Help")
+
+	var socket, address string
+	flag.StringVar(&socket, "socket", "", "Unix socket to listen on (default ~/.config/griffin/griffin.sock)")
+	flag.StringVar(&address, "addr", "", "Loopback address to listen on instead of a Unix socket, e.g. 127.0.0.1:7878")
+
+	if err := parseFlags(os.Args[2:]); err != nil {
+		return err
+	}
+
+	if showServeHelp {
+		printCommandHelp(executableName, command.name, false)
+		return nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
 
-		finder.FindProjects(executableName, alfredOutput, positionalArgs)
+	if address != "" {
+		return server.Serve(client, server.NETWORK_TCP, address)
+	}
+	if socket == "" {
+		socket = client.Configuration().SocketLocation
 	}
+	return server.Serve(client, server.NETWORK_UNIX, socket)
 }
 
-func runBuildProjectIndexCommand(command *Command, executableName string) {
-	projectindex.BuildProjectIndex()
+func runRpcCommand(command *Command, executableName string) error {
+	var showRpcHelp bool
+	flag.BoolVar(&showRpcHelp, "h", false, "Show Help")
+	flag.BoolVar(&showRpcHelp, "help", false, "Show Help")
+
+	if err := parseFlags(os.Args[2:]); err != nil {
+		return err
+	}
+
+	if showRpcHelp {
+		printCommandHelp(executableName, command.name, false)
+		return nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	return rpc.Serve(client, os.Stdin, os.Stdout)
 }
 
-func runShellIntegrationCommand(command *Command, executableName string) {
-	shell.GenerateIntegration()
+func runShellIntegrationCommand(command *Command, executableName string) error {
+	var showShellIntegrationHelp bool
+	flag.BoolVar(&showShellIntegrationHelp, "h", false, "Show Help")
+	flag.BoolVar(&showShellIntegrationHelp, "help", false, "Show Help")
+
+	var pickerName string
+	flag.StringVar(&pickerName, "picker", shell.PICKER_AUTO, "Picker for choosing between results: "+strings.Join(shell.SUPPORTED_PICKERS, ", ")+" (auto uses fzf when fzf and tree are installed)")
+
+	var shellName string
+	flag.StringVar(&shellName, "shell", "", "Shell to integrate with: "+strings.Join(shell.SUPPORTED_SHELLS, ", ")+" (default detected from $SHELL)")
+
+	// These override shellIntegration in config.json
+	flag.String("repo-function", "", "Name of the function switching to a repository (default "+shell.DEFAULT_FUNCTION_NAMES.SwitchToRepo+")")
+	flag.String("open-repo-function", "", "Name of the function opening a repository in its IDE (default "+shell.DEFAULT_FUNCTION_NAMES.OpenRepo+")")
+	flag.String("project-function", "", "Name of the function switching to a project (default "+shell.DEFAULT_FUNCTION_NAMES.SwitchToProject+")")
+	flag.String("open-project-function", "", "Name of the function opening a project in its IDE (default "+shell.DEFAULT_FUNCTION_NAMES.OpenProject+")")
+	flag.String("preview", "", "Shell command previewing the highlighted result, in which {} is its path (default with fzf: "+shell.DEFAULT_PREVIEW_COMMAND+")")
+	flag.Bool("auto-jump", true, "Switch to the only result of a query without picking it first")
+
+	if err := parseFlags(os.Args[2:]); err != nil {
+		return err
+	}
+
+	if showShellIntegrationHelp {
+		printCommandHelp(executableName, command.name, false)
+		return nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	shellConfiguration := client.Configuration().UserConfiguration.ShellConfiguration
+	options := shell.Options{
+		Picker:         pickerName,
+		Functions:      shell.FunctionNames(shellConfiguration.Functions),
+		PreviewCommand: shellConfiguration.PreviewCommand,
+		AutoJump:       shellConfiguration.ShouldAutoJump(),
+	}
+	flag.Visit(func(setFlag *flag.Flag) {
+		value := setFlag.Value.String()
+		switch setFlag.Name {
+		case "repo-function":
+			options.Functions.SwitchToRepo = value
+		case "open-repo-function":
+			options.Functions.OpenRepo = value
+		case "project-function":
+			options.Functions.SwitchToProject = value
+		case "open-project-function":
+			options.Functions.OpenProject = value
+		case "preview":
+			options.PreviewCommand = value
+		case "auto-jump":
+			options.AutoJump = value == "true"
+		}
+	})
+
+	if shellName == "" {
+		detectedShell, err := shell.DetectShell()
+		if err != nil {
+			return err
+		}
+		shellName = detectedShell
+	}
+
+	return shell.GenerateIntegration(shellName, options)
 }
 
-func runConfigureCommand(command *Command, executableName string) {
+func runConfigureCommand(command *Command, executableName string) error {
 	var configureHelp bool
 	flag.BoolVar(&configureHelp, "h", false, "Show Help")
 	flag.BoolVar(&configureHelp, "help", false, "Show Help")
 
 	// Register configuration flags so they show up in help
-	configuration.RegisterFlags()
+	configurationFlags := configuration.RegisterFlags()
 
-	flag.CommandLine.Parse(os.Args[2:])
+	if err := parseFlags(os.Args[2:]); err != nil {
+		return err
+	}
 
 	if configureHelp {
 		printCommandHelp(executableName, command.name, true)
-	} else if err := configuration.HandleConfiguration(); err != nil {
-		fmt.Printf("Configuration error: %v\n", err)
+		return nil
 	}
+
+	if err := configuration.HandleConfiguration(configurationFlags); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	return nil
 }
 
-func runInIDECommand(command *Command, executableName string) {
+func runInIDECommand(command *Command, executableName string) error {
 	var showInIDEHelp bool
 	var useAlternative bool
 
@@ -167,31 +500,105 @@ func runInIDECommand(command *Command, executableName string) {
 	flag.BoolVar(&showInIDEHelp, "help", false, "Show Help")
 	flag.BoolVar(&useAlternative, "use-alternative", false, "Use alternative IDE")
 
-	flag.CommandLine.Parse(os.Args[2:])
+	var ide string
+	var listIDEs, dryRun bool
+	flag.StringVar(&ide, "ide", "", "Use this IDE instead of the one configured for the project's language")
+	flag.BoolVar(&listIDEs, "list-ides", false, "List the IDEs configured for the project's language, starting with the one used by default")
+	flag.BoolVar(&dryRun, "dry-run", false, "Print the command that would open the project, and where its executable was found, without running it")
+
+	if err := parseFlags(os.Args[2:]); err != nil {
+		return err
+	}
 
 	if showInIDEHelp {
 		printCommandHelp(executableName, command.name, true)
-		return
+		return nil
 	}
 
 	args := flag.Args()
 	if len(args) < 1 {
-		fmt.Printf("Error: Project directory path is required\n\n")
-		printCommandHelp(executableName, command.name, true)
-		return
+		return &UsageError{Command: command.name, Message: "project directory or file path is required"}
 	}
 
-	projectDir := args[0]
-	if _, err := os.Stat(projectDir); os.IsNotExist(err) {
-		fmt.Printf("Error: Directory does not exist: %s\n", projectDir)
-		return
+	path, line, err := parsePathAndLine(args[0])
+	if err != nil {
+		return err
 	}
 
-	if useAlternative {
-		idelauncher.OpenInAlternativeIDE(projectDir)
-	} else {
-		idelauncher.OpenInIDE(projectDir)
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case listIDEs:
+		ides, err := client.IDEs(path)
+		if err != nil {
+			return err
+		}
+		for _, ide := range ides {
+			fmt.Println(ide)
+		}
+		return nil
+	case dryRun:
+		launch, err := client.ResolveIDE(path, line, ide, useAlternative)
+		if err != nil {
+			return err
+		}
+		fmt.Println(launch)
+		return nil
+	default:
+		return client.OpenFileInIDE(path, line, ide, useAlternative)
+	}
+}
+
+// FILE_LINE matches a file followed by a line, and optionally a column, e.g. main.go:42 or main.go:42:7
+var FILE_LINE = regexp.MustCompile(`^(.+?):(\d+)(?::\d+)?$`)
+
+// parsePathAndLine splits arg into an existing path and the line given after it, as in path/to/file.go:42. The
+// column is ignored.
+func parsePathAndLine(arg string) (string, int, error) {
+	if _, err := os.Stat(arg); err == nil {
+		return arg, 0, nil
+	}
+
+	if match := FILE_LINE.FindStringSubmatch(arg); match != nil {
+		if info, err := os.Stat(match[1]); err == nil && !info.IsDir() {
+			line, _ := strconv.Atoi(match[2])
+			return match[1], line, nil
+		}
 	}
+	return "", 0, fmt.Errorf("path does not exist: %s", arg)
+}
+
+func runRecordCommand(command *Command, executableName string) error {
+	var showRecordHelp bool
+	flag.BoolVar(&showRecordHelp, "h", false, "Show Help")
+	flag.BoolVar(&showRecordHelp, "help", false, "Show Help")
+
+	if err := parseFlags(os.Args[2:]); err != nil {
+		return err
+	}
+
+	if showRecordHelp {
+		printCommandHelp(executableName, command.name, false)
+		return nil
+	}
+
+	args := flag.Args()
+	if len(args) < 1 {
+		return &UsageError{Command: command.name, Message: "path is required"}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Record(args...); err != nil {
+		return fmt.Errorf("error saving history: %w", err)
+	}
+	return nil
 }
 
 func printCommandHelp(executableName string, commandName string, hasFilters bool) {
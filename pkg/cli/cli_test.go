@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePathAndLine(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		arg  string
+		path string
+		line int
+	}{
+		{dir, dir, 0},
+		{file, file, 0},
+		{file + ":42", file, 42},
+		{file + ":42:7", file, 42},
+	} {
+		if path, line, err := parsePathAndLine(test.arg); err != nil || path != test.path || line != test.line {
+			t.Errorf("Expected %s to be %s at line %d, got %s at %d (%v)", test.arg, test.path, test.line, path, line, err)
+		}
+	}
+
+	for _, arg := range []string{filepath.Join(dir, "other.go:42"), dir + ":42", file + ":line"} {
+		if _, _, err := parsePathAndLine(arg); err == nil {
+			t.Errorf("Expected %s to be rejected", arg)
+		}
+	}
+}
+
+func TestMissingToolMessage(t *testing.T) {
+	if message := missingToolMessage("fzf", "darwin"); !strings.Contains(message, "brew install fzf") {
+		t.Errorf("Expected a Homebrew hint on macOS, got %q", message)
+	}
+	if message := missingToolMessage("fzf", "linux"); strings.Contains(message, "brew") || !strings.Contains(message, "apt install fzf") {
+		t.Errorf("Expected a package manager hint on Linux, got %q", message)
+	}
+}
+
+func TestUsageError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &UsageError{Command: "record", Message: "path is required"})
+	if code := exitCode(err); code != EXIT_USAGE {
+		t.Errorf("Expected exit code %d for a usage error, got %d", EXIT_USAGE, code)
+	}
+	if message := errorMessage(err); !strings.Contains(message, "path is required") || !strings.Contains(message, "griffin record -h") {
+		t.Errorf("Expected the message to point at the command's help, got %q", message)
+	}
+}
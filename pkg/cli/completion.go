@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"ronkitay.com/griffin/pkg/finder"
+	"ronkitay.com/griffin/pkg/griffin"
+	"ronkitay.com/griffin/pkg/shell"
+)
+
+// HIDDEN_COMMANDS are run by scripts griffin generates, and are left out of the help
+var HIDDEN_COMMANDS = []Command{
+	{"__complete", "Prints the completions of the last argument", runCompleteCommand},
+}
+
+// FLAG_VALUES are the values completed after flags that take one of a known set of values
+var FLAG_VALUES = map[string][]string{
+	"shell":  shell.SUPPORTED_SHELLS,
+	"picker": shell.SUPPORTED_PICKERS,
+	"format": finder.SUPPORTED_FORMATS,
+}
+
+// errListingFlags stops a command right after it registered its flags, when they are listed for completion
+var errListingFlags = errors.New("listing flags")
+
+var listingFlags bool
+
+// parseFlags parses the flags of the running command. Every command handler calls it before doing anything else,
+// which lets __complete list the flags of a command by running its handler with listingFlags set.
+func parseFlags(args []string) error {
+	if listingFlags {
+		return errListingFlags
+	}
+	flag.CommandLine.Parse(args)
+	return nil
+}
+
+func runCompletionCommand(command *Command, executableName string) error {
+	var showCompletionHelp bool
+	flag.BoolVar(&showCompletionHelp, "h", false, "Show Help")
+	flag.BoolVar(&showCompletionHelp, "help", false, "Show Help")
+
+	if err := parseFlags(os.Args[2:]); err != nil {
+		return err
+	}
+
+	if showCompletionHelp {
+		fmt.Println("Usage:")
+		fmt.Printf("  %s %s [%s]\n", executableName, command.name, strings.Join(shell.SUPPORTED_SHELLS, "|"))
+		fmt.Println("The shell is detected from $SHELL when it is not given.")
+		return nil
+	}
+
+	shellName := flag.Arg(0)
+	if shellName == "" {
+		detectedShell, err := shell.DetectShell()
+		if err != nil {
+			return err
+		}
+		shellName = detectedShell
+	}
+
+	return shell.GenerateCompletion(shellName)
+}
+
+// runCompleteCommand prints the completions of its last argument, one per line. The completion scripts pass it the
+// words following `griffin` on the command line, ending with the word being completed (which may be empty).
+func runCompleteCommand(command *Command, executableName string) error {
+	for _, candidate := range completions(os.Args[2:]) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+func completions(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current, previous := words[len(words)-1], words[:len(words)-1]
+
+	if len(previous) == 0 {
+		var names []string
+		for _, command := range COMMANDS {
+			names = append(names, command.name)
+		}
+		return withPrefix(names, current)
+	}
+
+	command := matchVisibleCommand(previous[0])
+	if command == nil {
+		return nil
+	}
+
+	if values, found := FLAG_VALUES[strings.TrimLeft(previous[len(previous)-1], "-")]; found && len(previous) > 1 {
+		return withPrefix(values, current)
+	}
+
+	if strings.HasPrefix(current, "-") {
+		flags, _ := listFlags(command)
+		return withPrefix(flags, current)
+	}
+
+	switch command.name {
+	case "find-repo":
+		return repoNames(current)
+	case "find-project":
+		return projectNames(current)
+	case "pick":
+		if slices.Contains(previous, "-projects") || slices.Contains(previous, "--projects") {
+			return projectNames(current)
+		}
+		return repoNames(current)
+	case "completion":
+		if len(previous) == 1 {
+			return withPrefix(shell.SUPPORTED_SHELLS, current)
+		}
+	}
+	return nil
+}
+
+func matchVisibleCommand(commandName string) *Command {
+	for _, command := range COMMANDS {
+		if command.name == commandName {
+			return &command
+		}
+	}
+	return nil
+}
+
+// listFlags returns the flags of command, by running its handler until it parses them
+func listFlags(command *Command) ([]string, error) {
+	originalFlags := flag.CommandLine
+	flag.CommandLine = flag.NewFlagSet(command.name, flag.ContinueOnError)
+	flag.CommandLine.SetOutput(io.Discard)
+	listingFlags = true
+	defer func() {
+		flag.CommandLine = originalFlags
+		listingFlags = false
+	}()
+
+	if err := command.handler(command, os.Args[0]); !errors.Is(err, errListingFlags) {
+		return nil, fmt.Errorf("command %s did not stop at parseFlags: %v", command.name, err)
+	}
+
+	var flags []string
+	flag.CommandLine.VisitAll(func(commandFlag *flag.Flag) {
+		if commandFlag.Name != "h" {
+			flags = append(flags, "-"+commandFlag.Name)
+		}
+	})
+	return flags, nil
+}
+
+// repoNames returns the names of the repositories matching query, best match first
+func repoNames(query string) []string {
+	client, err := newClient()
+	if err != nil {
+		return nil
+	}
+
+	repos, err := client.FindRepos(griffin.RepoSearchOptions{}, strings.Fields(query))
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, repo := range repos {
+		names = append(names, repo.Item.FullName)
+	}
+	return distinct(names)
+}
+
+// projectNames returns the names of the projects matching query, best match first
+func projectNames(query string) []string {
+	client, err := newClient()
+	if err != nil {
+		return nil
+	}
+
+	projects, err := client.FindProjects(griffin.SearchOptions{}, strings.Fields(query))
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, project := range projects {
+		names = append(names, project.Item.FullName)
+	}
+	return distinct(names)
+}
+
+func withPrefix(candidates []string, prefix string) []string {
+	var result []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+func distinct(values []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
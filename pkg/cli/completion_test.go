@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestListFlags_AllCommands(t *testing.T) {
+	for _, command := range COMMANDS {
+		flags, err := listFlags(&command)
+		if err != nil {
+			t.Errorf("Listing the flags of %s failed: %v", command.name, err)
+			continue
+		}
+		if !slices.Contains(flags, "-help") {
+			t.Errorf("Expected %s to have a -help flag, got %v", command.name, flags)
+		}
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	for _, test := range []struct {
+		words    []string
+		expected []string
+	}{
+		{[]string{"find-"}, []string{"find-repo", "find-project"}},
+		{[]string{"__comp"}, nil},
+		{[]string{"find-repo", "-no"}, []string{"-no-history", "-noarchive", "-nodir"}},
+		{[]string{"shell-integration", "-shell", "f"}, []string{"fish"}},
+		{[]string{"shell-integration", "--picker", ""}, []string{"auto", "fzf", "native"}},
+		{[]string{"completion", "ba"}, []string{"bash"}},
+		{[]string{"completion", "bash", ""}, nil},
+		{[]string{"unknown", ""}, nil},
+	} {
+		if actual := completions(test.words); !slices.Equal(actual, test.expected) {
+			t.Errorf("completions(%q) = %q, expected %q", test.words, actual, test.expected)
+		}
+	}
+}
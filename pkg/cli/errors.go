@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"ronkitay.com/griffin/pkg/configuration"
+	csvHelper "ronkitay.com/griffin/pkg/csv"
+	"ronkitay.com/griffin/pkg/finder"
+	"ronkitay.com/griffin/pkg/idelauncher"
+	"ronkitay.com/griffin/pkg/matcher"
+	"ronkitay.com/griffin/pkg/picker"
+	"ronkitay.com/griffin/pkg/shell"
+)
+
+// Exit codes of griffin commands
+const (
+	EXIT_SUCCESS                   = 0
+	EXIT_FAILURE                   = 1
+	EXIT_INVALID_QUERY             = 3
+	EXIT_MISSING_IDE_CONFIGURATION = 12
+	EXIT_CANCELLED                 = 130
+	EXIT_USAGE                     = 255
+)
+
+// UsageError is returned when a command is run without its required arguments
+type UsageError struct {
+	Command string
+	Message string
+}
+
+func (err *UsageError) Error() string {
+	return err.Message
+}
+
+// exitWithError prints a user-facing message for err to stderr and exits with the matching exit code.
+func exitWithError(err error) {
+	if message := errorMessage(err); message != "" {
+		fmt.Fprintln(os.Stderr, message)
+	}
+	os.Exit(exitCode(err))
+}
+
+func exitCode(err error) int {
+	var invalidPatternError *matcher.InvalidPatternError
+	var unknownFieldError *matcher.UnknownFieldError
+	var invalidFormatError *finder.InvalidFormatError
+	var usageError *UsageError
+
+	switch {
+	case err == nil:
+		return EXIT_SUCCESS
+	case errors.As(err, &usageError):
+		return EXIT_USAGE
+	case errors.As(err, &invalidPatternError), errors.As(err, &unknownFieldError), errors.As(err, &invalidFormatError):
+		return EXIT_INVALID_QUERY
+	case errors.Is(err, idelauncher.ErrMissingDefaultIDE):
+		return EXIT_MISSING_IDE_CONFIGURATION
+	case errors.Is(err, picker.ErrCancelled):
+		return EXIT_CANCELLED
+	default:
+		return EXIT_FAILURE
+	}
+}
+
+func errorMessage(err error) string {
+	var missingIndexError *csvHelper.MissingIndexError
+	var corruptIndexError *csvHelper.CorruptIndexError
+	var invalidConfigError *configuration.InvalidConfigError
+	var invalidPatternError *matcher.InvalidPatternError
+	var unknownFieldError *matcher.UnknownFieldError
+	var invalidFormatError *finder.InvalidFormatError
+	var missingToolError *shell.MissingToolError
+	var unresolvedIDEError *idelauncher.UnresolvedIDEError
+	var usageError *UsageError
+
+	switch {
+	case errors.Is(err, picker.ErrCancelled):
+		// Closing the picker is not an error worth reporting
+		return ""
+	case errors.As(err, &usageError):
+		return fmt.Sprintf("Error: %s - run 'griffin %s -h' for usage", usageError.Message, usageError.Command)
+	case errors.As(err, &missingIndexError):
+		return fmt.Sprintf("Index %s not found - build it with 'griffin build-repo-index' and 'griffin build-project-index'", missingIndexError.Path)
+	case errors.As(err, &corruptIndexError):
+		return fmt.Sprintf("Could not load index %s: %v", corruptIndexError.Path, corruptIndexError.Err)
+	case errors.As(err, &invalidConfigError):
+		return fmt.Sprintf("Error reading configuration %s: %v", invalidConfigError.Path, invalidConfigError.Err)
+	case errors.As(err, &invalidPatternError), errors.As(err, &unknownFieldError):
+		return fmt.Sprintf("Invalid query: %v", err)
+	case errors.As(err, &invalidFormatError):
+		if invalidFormatError.Err != nil {
+			return fmt.Sprintf("Invalid format template: %v", invalidFormatError.Err)
+		}
+		return fmt.Sprintf("Unsupported format '%s' (supported formats: %s)", invalidFormatError.Format, strings.Join(finder.SUPPORTED_FORMATS, ", "))
+	case errors.Is(err, idelauncher.ErrMissingDefaultIDE):
+		return "Missing DefaultIDE configuration - set it with 'griffin configure -default-ide <IDE>'"
+	case errors.As(err, &unresolvedIDEError):
+		return fmt.Sprintf("IDE '%s' not found - searched %s.\nConfigure its full path or command with 'griffin configure'", unresolvedIDEError.IDE, strings.Join(unresolvedIDEError.Searched, "; "))
+	case errors.As(err, &missingToolError):
+		return missingToolMessage(missingToolError.Tool, runtime.GOOS)
+	default:
+		return fmt.Sprintf("Error: %v", err)
+	}
+}
+
+// missingToolMessage suggests installing tool with Homebrew on macOS, and with the system's package manager elsewhere
+func missingToolMessage(tool string, goos string) string {
+	if goos == "darwin" {
+		return fmt.Sprintf("Tool '%s' not found in the PATH.\nInstall it using the following command:\nbrew install %s\n", tool, tool)
+	}
+	return fmt.Sprintf("Tool '%s' not found in the PATH.\nInstall it with your package manager, e.g. 'sudo apt install %s' or 'sudo dnf install %s'\n", tool, tool, tool)
+}
@@ -4,38 +4,77 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
 
+	"ronkitay.com/griffin/pkg/language"
 	"ronkitay.com/griffin/pkg/terminal"
 )
 
-type IdeConfiguration struct {
-	DefaultIDE            string `json:"default"`
-	DefaultIDEAlternative string `json:"defaultAlternative"`
-	GoLang                string `json:"go"`
-	GoLangAlternative     string `json:"goAlternative"`
-	Java                  string `json:"java"`
-	JavaAlternative       string `json:"javaAlternative"`
-	Kotlin                string `json:"kotlin"`
-	KotlinAlternative     string `json:"kotlinAlternative"`
-	Rust                  string `json:"rust"`
-	RustAlternative       string `json:"rustAlternative"`
-	Python                string `json:"python"`
-	PythonAlternative     string `json:"pythonAlternative"`
-	NodeJS                string `json:"node"`
-	NodeJSAlternative     string `json:"nodeAlternative"`
+// ShellFunctions are the names of the functions the shell integration defines. Empty names keep their default.
+type ShellFunctions struct {
+	SwitchToRepo    string `json:"switchToRepo,omitempty"`
+	OpenRepo        string `json:"openRepo,omitempty"`
+	SwitchToProject string `json:"switchToProject,omitempty"`
+	OpenProject     string `json:"openProject,omitempty"`
+}
+
+type ShellConfiguration struct {
+	Functions ShellFunctions `json:"functions"`
+	// PreviewCommand previews the highlighted result when picking one, with {} standing for its path
+	PreviewCommand string `json:"previewCommand,omitempty"`
+	// AutoJump switches to the only result of a query without picking it first - true when it is not set
+	AutoJump *bool `json:"autoJump,omitempty"`
+}
+
+// ShouldAutoJump returns AutoJump, which is true when it is not set
+func (shellConfiguration ShellConfiguration) ShouldAutoJump() bool {
+	return shellConfiguration.AutoJump == nil || *shellConfiguration.AutoJump
 }
 
 type UserConfiguration struct {
-	RepoRoots        []string         `json:"repoRoots"`
-	IdeConfiguration IdeConfiguration `json:"ideConfiguration"`
+	RepoRoots          []string           `json:"repoRoots"`
+	IdeConfiguration   IdeConfiguration   `json:"ideConfiguration"`
+	ShellConfiguration ShellConfiguration `json:"shellIntegration"`
+	// LanguageRules detect the language of projects, before the built-in language.DEFAULT_RULES
+	LanguageRules []language.Rule `json:"languageRules,omitempty"`
+	// LanguagePriority chooses the primary language of projects in which several languages are detected
+	LanguagePriority language.Priority `json:"languagePriority"`
 }
 
 type Configuration struct {
-	RepoListLocation    string
-	ProjectListLocation string
-	UserConfiguration   UserConfiguration
+	RepoListLocation       string
+	RepoIndexStateLocation string
+	ProjectListLocation    string
+	HistoryLocation        string
+	SocketLocation         string
+	UserConfiguration      UserConfiguration
+	// Warnings receives the problems that do not stop indexing, such as unreadable directories. They are dropped
+	// when it is nil.
+	Warnings io.Writer
+}
+
+// Warn writes a warning to Warnings, if set.
+func (configuration Configuration) Warn(format string, args ...any) {
+	if configuration.Warnings != nil {
+		fmt.Fprintf(configuration.Warnings, format+"\n", args...)
+	}
+}
+
+// InvalidConfigError is returned when config.json cannot be read or is not valid JSON.
+type InvalidConfigError struct {
+	Path string
+	Err  error
+}
+
+func (err *InvalidConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration %s: %v", err.Path, err.Err)
+}
+
+func (err *InvalidConfigError) Unwrap() error {
+	return err.Err
 }
 
 type ConfigurationManager struct {
@@ -44,7 +83,7 @@ type ConfigurationManager struct {
 }
 
 func NewConfigurationManager() (*ConfigurationManager, error) {
-	configDir := os.Getenv("HOME") + "/.config/griffin"
+	configDir := DefaultDirectory()
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return nil, fmt.Errorf("error creating config directory: %v", err)
 	}
@@ -56,11 +95,11 @@ func NewConfigurationManager() (*ConfigurationManager, error) {
 	if exists, _ := fileExists(configFile); exists {
 		data, err := os.ReadFile(configFile)
 		if err != nil {
-			return nil, fmt.Errorf("error reading config file: %v", err)
+			return nil, &InvalidConfigError{Path: configFile, Err: err}
 		}
 
 		if err := json.Unmarshal(data, &config); err != nil {
-			return nil, fmt.Errorf("error parsing config file: %v", err)
+			return nil, &InvalidConfigError{Path: configFile, Err: err}
 		}
 	}
 
@@ -94,8 +133,12 @@ func (cm *ConfigurationManager) AddRepoRoot(path string) error {
 }
 
 func (cm *ConfigurationManager) GetRepoRoots() ([]string, error) {
+	return expandPaths(cm.config.RepoRoots)
+}
+
+func expandPaths(paths []string) ([]string, error) {
 	var expandedPaths []string
-	for _, root := range cm.config.RepoRoots {
+	for _, root := range paths {
 		expandedPath, err := expandPath(root)
 		if err != nil {
 			return nil, fmt.Errorf("error expanding path %s: %v", root, err)
@@ -130,56 +173,28 @@ func (cm *ConfigurationManager) SetDefaultIDE(ide string) {
 	cm.config.IdeConfiguration.DefaultIDE = ide
 }
 
-func (cm *ConfigurationManager) SetGoIDE(ide string) {
-	cm.config.IdeConfiguration.GoLang = ide
-}
-
-func (cm *ConfigurationManager) SetJavaIDE(ide string) {
-	cm.config.IdeConfiguration.Java = ide
-}
-
-func (cm *ConfigurationManager) SetKotlinIDE(ide string) {
-	cm.config.IdeConfiguration.Kotlin = ide
-}
-
-func (cm *ConfigurationManager) SetPythonIDE(ide string) {
-	cm.config.IdeConfiguration.Python = ide
-}
-
-func (cm *ConfigurationManager) SetNodeIDE(ide string) {
-	cm.config.IdeConfiguration.NodeJS = ide
-}
-
-func (cm *ConfigurationManager) SetRustIDE(ide string) {
-	cm.config.IdeConfiguration.Rust = ide
-}
-
 func (cm *ConfigurationManager) SetDefaultIDEAlternative(ide string) {
 	cm.config.IdeConfiguration.DefaultIDEAlternative = ide
 }
 
-func (cm *ConfigurationManager) SetGoIDEAlternative(ide string) {
-	cm.config.IdeConfiguration.GoLangAlternative = ide
+func (cm *ConfigurationManager) SetLanguageIDE(language string, ide string) {
+	ides := cm.config.IdeConfiguration.Languages[strings.ToLower(language)]
+	ides.Primary = ide
+	cm.config.IdeConfiguration.setLanguage(language, ides)
 }
 
-func (cm *ConfigurationManager) SetJavaIDEAlternative(ide string) {
-	cm.config.IdeConfiguration.JavaAlternative = ide
+func (cm *ConfigurationManager) SetLanguageIDEAlternative(language string, ide string) {
+	ides := cm.config.IdeConfiguration.Languages[strings.ToLower(language)]
+	ides.Alternative = ide
+	cm.config.IdeConfiguration.setLanguage(language, ides)
 }
 
-func (cm *ConfigurationManager) SetKotlinIDEAlternative(ide string) {
-	cm.config.IdeConfiguration.KotlinAlternative = ide
-}
-
-func (cm *ConfigurationManager) SetPythonIDEAlternative(ide string) {
-	cm.config.IdeConfiguration.PythonAlternative = ide
-}
-
-func (cm *ConfigurationManager) SetNodeIDEAlternative(ide string) {
-	cm.config.IdeConfiguration.NodeJSAlternative = ide
-}
-
-func (cm *ConfigurationManager) SetRustIDEAlternative(ide string) {
-	cm.config.IdeConfiguration.RustAlternative = ide
+func (cm *ConfigurationManager) AddLanguageIDE(language string, ide string) {
+	ides := cm.config.IdeConfiguration.Languages[strings.ToLower(language)]
+	if !slices.Contains(ides.Additional, ide) {
+		ides.Additional = append(ides.Additional, ide)
+	}
+	cm.config.IdeConfiguration.setLanguage(language, ides)
 }
 
 func (cm *ConfigurationManager) Save() error {
@@ -213,137 +228,163 @@ func fileExists(filename string) (bool, error) {
 	return true, nil
 }
 
-func LoadConfiguration() Configuration {
-	configurationDirectory := os.Getenv("HOME") + "/.config/griffin"
-	repoListLocation := configurationDirectory + "/repo.list"
-	projectListLocation := configurationDirectory + "/project.list"
+// DefaultDirectory is where griffin keeps its configuration and indexes: $HOME/.config/griffin.
+func DefaultDirectory() string {
+	return os.Getenv("HOME") + "/.config/griffin"
+}
+
+// NewConfiguration builds a configuration that keeps its indexes in directory, without reading any files.
+func NewConfiguration(directory string, userConfiguration UserConfiguration) Configuration {
+	return Configuration{
+		RepoListLocation:       directory + "/repo.list",
+		RepoIndexStateLocation: directory + "/repo.state.json",
+		ProjectListLocation:    directory + "/project.list",
+		HistoryLocation:        directory + "/history.list",
+		SocketLocation:         directory + "/griffin.sock",
+		UserConfiguration:      userConfiguration,
+	}
+}
+
+// LoadConfiguration reads the configuration from DefaultDirectory.
+// It fails with an *InvalidConfigError if config.json cannot be read.
+func LoadConfiguration() (Configuration, error) {
+	return LoadConfigurationFrom(DefaultDirectory())
+}
 
+// LoadConfigurationFrom reads the configuration from directory/config.json, if it exists.
+// It fails with an *InvalidConfigError if config.json cannot be read.
+func LoadConfigurationFrom(configurationDirectory string) (Configuration, error) {
 	var userConfiguration UserConfiguration
 
-	if exists, _ := fileExists(configurationDirectory + "/config.json"); exists == true {
-		jsonConfigFile, fileOpenError := os.ReadFile(configurationDirectory + "/config.json")
+	configFile := configurationDirectory + "/config.json"
+	if exists, _ := fileExists(configFile); exists == true {
+		jsonConfigFile, fileOpenError := os.ReadFile(configFile)
 		if fileOpenError != nil {
-			os.Exit(1)
+			return Configuration{}, &InvalidConfigError{Path: configFile, Err: fileOpenError}
 		}
 
 		jsonReadError := json.Unmarshal(jsonConfigFile, &userConfiguration)
 		if jsonReadError != nil {
-			panic("Error reading JSON Configuration:" + jsonReadError.Error())
+			return Configuration{}, &InvalidConfigError{Path: configFile, Err: jsonReadError}
 		}
 	} else {
 		userConfiguration = UserConfiguration{}
 	}
 
-	return Configuration{RepoListLocation: repoListLocation, ProjectListLocation: projectListLocation, UserConfiguration: userConfiguration}
+	return NewConfiguration(configurationDirectory, userConfiguration), nil
+}
+
+// RepoRoots returns the configured repository roots, with environment variables such as ${HOME} expanded.
+func (configuration Configuration) RepoRoots() ([]string, error) {
+	return expandPaths(configuration.UserConfiguration.RepoRoots)
 }
 
-func RegisterFlags() {
-	flag.String("add-repo-root", "", "Add a repository root directory")
-	flag.String("default-ide", "", "Set default IDE")
-	flag.String("go-ide", "", "Set Go IDE")
-	flag.String("java-ide", "", "Set Java IDE")
-	flag.String("kotlin-ide", "", "Set Kotlin IDE")
-	flag.String("python-ide", "", "Set Python IDE")
-	flag.String("node-ide", "", "Set NodeJS IDE")
-	flag.String("rust-ide", "", "Set Rust IDE")
-	flag.String("default-ide-alt", "", "Set default IDE alternative")
-	flag.String("go-ide-alt", "", "Set Go IDE alternative")
-	flag.String("java-ide-alt", "", "Set Java IDE alternative")
-	flag.String("kotlin-ide-alt", "", "Set Kotlin IDE alternative")
-	flag.String("python-ide-alt", "", "Set Python IDE alternative")
-	flag.String("node-ide-alt", "", "Set NodeJS IDE alternative")
-	flag.String("rust-ide-alt", "", "Set Rust IDE alternative")
+// LanguageDetector returns the detector of project languages, using the configured LanguageRules first and choosing
+// primary languages by LanguagePriority.
+func (configuration Configuration) LanguageDetector() (*language.Detector, error) {
+	return language.NewDetector(configuration.UserConfiguration.LanguageRules, configuration.UserConfiguration.LanguagePriority)
 }
 
-func HandleConfiguration() error {
+// LanguageIDEFlag collects repeated `-flag language=IDE` values
+type LanguageIDEFlag []LanguageIDE
+
+type LanguageIDE struct {
+	Language string
+	IDE      string
+}
+
+func (languageIDEs *LanguageIDEFlag) String() string {
+	var values []string
+	for _, languageIDE := range *languageIDEs {
+		values = append(values, languageIDE.Language+"="+languageIDE.IDE)
+	}
+	return strings.Join(values, ",")
+}
+
+func (languageIDEs *LanguageIDEFlag) Set(value string) error {
+	languageName, ide, found := strings.Cut(value, "=")
+	if !found || languageName == "" || ide == "" {
+		return fmt.Errorf("expected language=IDE, got %q", value)
+	}
+	*languageIDEs = append(*languageIDEs, LanguageIDE{Language: languageName, IDE: ide})
+	return nil
+}
+
+// ConfigurationFlags are the flags of `griffin configure`
+type ConfigurationFlags struct {
+	AddRepoRoot           string
+	DefaultIDE            string
+	DefaultIDEAlternative string
+	IDEs                  LanguageIDEFlag
+	AlternativeIDEs       LanguageIDEFlag
+	AdditionalIDEs        LanguageIDEFlag
+}
+
+// LEGACY_LANGUAGE_FLAGS are the per-language flags configure had before -ide, by the language they set
+var LEGACY_LANGUAGE_FLAGS = map[string]string{
+	"go":     language.GO,
+	"java":   language.JAVA,
+	"kotlin": language.KOTLIN,
+	"python": language.PYTHON,
+	"node":   language.NODE,
+	"rust":   language.RUST,
+}
+
+func RegisterFlags() *ConfigurationFlags {
+	flags := &ConfigurationFlags{}
+	flag.StringVar(&flags.AddRepoRoot, "add-repo-root", "", "Add a repository root directory")
+	flag.StringVar(&flags.DefaultIDE, "default-ide", "", "Set default IDE")
+	flag.StringVar(&flags.DefaultIDEAlternative, "default-ide-alt", "", "Set default IDE alternative")
+	flag.Var(&flags.IDEs, "ide", "Set the IDE of a language, as language=IDE (repeatable)")
+	flag.Var(&flags.AlternativeIDEs, "alternative-ide", "Set the alternative IDE of a language, as language=IDE (repeatable)")
+	flag.Var(&flags.AdditionalIDEs, "additional-ide", "Add an IDE that can open a language, as language=IDE (repeatable)")
+
+	for flagPrefix, languageName := range LEGACY_LANGUAGE_FLAGS {
+		flag.Func(flagPrefix+"-ide", "Same as -ide "+languageName+"=IDE", func(ide string) error {
+			return flags.IDEs.Set(languageName + "=" + ide)
+		})
+		flag.Func(flagPrefix+"-ide-alt", "Same as -alternative-ide "+languageName+"=IDE", func(ide string) error {
+			return flags.AlternativeIDEs.Set(languageName + "=" + ide)
+		})
+	}
+	return flags
+}
+
+func HandleConfiguration(flags *ConfigurationFlags) error {
 	changesMade := false
-	flag.Parse()
-
-	// Get values after flags have been parsed
-	addRepoRoot := flag.Lookup("add-repo-root").Value.String()
-	defaultIDE := flag.Lookup("default-ide").Value.String()
-	goIDE := flag.Lookup("go-ide").Value.String()
-	javaIDE := flag.Lookup("java-ide").Value.String()
-	kotlinIDE := flag.Lookup("kotlin-ide").Value.String()
-	pythonIDE := flag.Lookup("python-ide").Value.String()
-	nodeIDE := flag.Lookup("node-ide").Value.String()
-	rustIDE := flag.Lookup("rust-ide").Value.String()
-	defaultIDEAlt := flag.Lookup("default-ide-alt").Value.String()
-	goIDEAlt := flag.Lookup("go-ide-alt").Value.String()
-	javaIDEAlt := flag.Lookup("java-ide-alt").Value.String()
-	kotlinIDEAlt := flag.Lookup("kotlin-ide-alt").Value.String()
-	pythonIDEAlt := flag.Lookup("python-ide-alt").Value.String()
-	nodeIDEAlt := flag.Lookup("node-ide-alt").Value.String()
-	rustIDEAlt := flag.Lookup("rust-ide-alt").Value.String()
 
 	configManager, err := NewConfigurationManager()
 	if err != nil {
-		return fmt.Errorf("error initializing configuration: %v", err)
+		return err
 	}
 
 	// Update configuration based on provided flags
-	if addRepoRoot != "" {
-		if err := configManager.AddRepoRoot(addRepoRoot); err != nil {
+	if flags.AddRepoRoot != "" {
+		if err := configManager.AddRepoRoot(flags.AddRepoRoot); err != nil {
 			return fmt.Errorf("error adding repository root: %v", err)
 		}
-	}
-
-	// Update IDE configurations
-	if defaultIDE != "" {
-		configManager.SetDefaultIDE(defaultIDE)
-		changesMade = true
-	}
-	if goIDE != "" {
-		configManager.SetGoIDE(goIDE)
-		changesMade = true
-	}
-	if javaIDE != "" {
-		configManager.SetJavaIDE(javaIDE)
-		changesMade = true
-	}
-	if kotlinIDE != "" {
-		configManager.SetKotlinIDE(kotlinIDE)
-		changesMade = true
-	}
-	if pythonIDE != "" {
-		configManager.SetPythonIDE(pythonIDE)
-		changesMade = true
-	}
-	if nodeIDE != "" {
-		configManager.SetNodeIDE(nodeIDE)
-		changesMade = true
-	}
-	if rustIDE != "" {
-		configManager.SetRustIDE(rustIDE)
 		changesMade = true
 	}
 
-	if defaultIDEAlt != "" {
-		configManager.SetDefaultIDEAlternative(defaultIDEAlt)
-		changesMade = true
-	}
-	if goIDEAlt != "" {
-		configManager.SetGoIDEAlternative(goIDEAlt)
-		changesMade = true
-	}
-	if javaIDEAlt != "" {
-		configManager.SetJavaIDEAlternative(javaIDEAlt)
+	// Update IDE configurations
+	if flags.DefaultIDE != "" {
+		configManager.SetDefaultIDE(flags.DefaultIDE)
 		changesMade = true
 	}
-	if kotlinIDEAlt != "" {
-		configManager.SetKotlinIDEAlternative(kotlinIDEAlt)
+	if flags.DefaultIDEAlternative != "" {
+		configManager.SetDefaultIDEAlternative(flags.DefaultIDEAlternative)
 		changesMade = true
 	}
-	if pythonIDEAlt != "" {
-		configManager.SetPythonIDEAlternative(pythonIDEAlt)
+	for _, languageIDE := range flags.IDEs {
+		configManager.SetLanguageIDE(languageIDE.Language, languageIDE.IDE)
 		changesMade = true
 	}
-	if nodeIDEAlt != "" {
-		configManager.SetNodeIDEAlternative(nodeIDEAlt)
+	for _, languageIDE := range flags.AlternativeIDEs {
+		configManager.SetLanguageIDEAlternative(languageIDE.Language, languageIDE.IDE)
 		changesMade = true
 	}
-	if rustIDEAlt != "" {
-		configManager.SetRustIDEAlternative(rustIDEAlt)
+	for _, languageIDE := range flags.AdditionalIDEs {
+		configManager.AddLanguageIDE(languageIDE.Language, languageIDE.IDE)
 		changesMade = true
 	}
 
@@ -363,12 +404,21 @@ func HandleConfiguration() error {
 	}
 	fmt.Println("  " + terminal.GREEN_COLOR + "IDE Configuration" + terminal.RESET_COLORS)
 	fmt.Printf("    Default IDE: %s\n", config.IdeConfiguration.DefaultIDE)
-	if config.IdeConfiguration.GoLang != "" { fmt.Printf("    Go IDE: %s\n", config.IdeConfiguration.GoLang) }
-	if config.IdeConfiguration.Java != "" { fmt.Printf("    Java IDE: %s\n", config.IdeConfiguration.Java) }
-	if config.IdeConfiguration.Kotlin != "" { fmt.Printf("    Kotlin IDE: %s\n", config.IdeConfiguration.Kotlin) }
-	if config.IdeConfiguration.Python != "" { fmt.Printf("    Python IDE: %s\n", config.IdeConfiguration.Python) }
-	if config.IdeConfiguration.NodeJS != "" { fmt.Printf("    NodeJS IDE: %s\n", config.IdeConfiguration.NodeJS) }
-	if config.IdeConfiguration.Rust != "" { fmt.Printf("    Rust IDE: %s\n", config.IdeConfiguration.Rust) }
+	if config.IdeConfiguration.DefaultIDEAlternative != "" {
+		fmt.Printf("    Default IDE alternative: %s\n", config.IdeConfiguration.DefaultIDEAlternative)
+	}
+	for _, languageName := range config.IdeConfiguration.SortedLanguages() {
+		ides := config.IdeConfiguration.Languages[languageName]
+		if ides.Primary != "" {
+			fmt.Printf("    %s IDE: %s\n", languageName, ides.Primary)
+		}
+		if ides.Alternative != "" {
+			fmt.Printf("    %s IDE alternative: %s\n", languageName, ides.Alternative)
+		}
+		if len(ides.Additional) > 0 {
+			fmt.Printf("    %s additional IDEs: %s\n", languageName, strings.Join(ides.Additional, ", "))
+		}
+	}
 
 	return nil
 }
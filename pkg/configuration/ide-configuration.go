@@ -0,0 +1,146 @@
+package configuration
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"ronkitay.com/griffin/pkg/language"
+)
+
+// LanguageIDEs are the IDEs configured for a language
+type LanguageIDEs struct {
+	Primary     string `json:"primary,omitempty"`
+	Alternative string `json:"alternative,omitempty"`
+	// Additional IDEs can be chosen explicitly, e.g. with `griffin open-in-ide -ide`
+	Additional []string `json:"additional,omitempty"`
+}
+
+type IdeConfiguration struct {
+	DefaultIDE            string `json:"default"`
+	DefaultIDEAlternative string `json:"defaultAlternative"`
+	// Languages maps the languages detected by language.Detector to their IDEs
+	Languages map[string]LanguageIDEs `json:"languages,omitempty"`
+}
+
+// legacyIdeConfiguration has the per-language fields config.json had before Languages
+type legacyIdeConfiguration struct {
+	GoLang            string `json:"go"`
+	GoLangAlternative string `json:"goAlternative"`
+	Java              string `json:"java"`
+	JavaAlternative   string `json:"javaAlternative"`
+	Kotlin            string `json:"kotlin"`
+	KotlinAlternative string `json:"kotlinAlternative"`
+	Rust              string `json:"rust"`
+	RustAlternative   string `json:"rustAlternative"`
+	Python            string `json:"python"`
+	PythonAlternative string `json:"pythonAlternative"`
+	NodeJS            string `json:"node"`
+	NodeJSAlternative string `json:"nodeAlternative"`
+}
+
+// languages returns the legacy fields by language, as primary and alternative IDEs
+func (legacy legacyIdeConfiguration) languages() map[string]LanguageIDEs {
+	return map[string]LanguageIDEs{
+		language.GO:     {Primary: legacy.GoLang, Alternative: legacy.GoLangAlternative},
+		language.JAVA:   {Primary: legacy.Java, Alternative: legacy.JavaAlternative},
+		language.KOTLIN: {Primary: legacy.Kotlin, Alternative: legacy.KotlinAlternative},
+		language.RUST:   {Primary: legacy.Rust, Alternative: legacy.RustAlternative},
+		language.PYTHON: {Primary: legacy.Python, Alternative: legacy.PythonAlternative},
+		language.NODE:   {Primary: legacy.NodeJS, Alternative: legacy.NodeJSAlternative},
+	}
+}
+
+// UnmarshalJSON reads the legacy per-language fields as well, for languages that are not in Languages. They are not
+// written back - saving the configuration moves them to Languages.
+func (ideConfiguration *IdeConfiguration) UnmarshalJSON(data []byte) error {
+	type plainIdeConfiguration IdeConfiguration
+	var current plainIdeConfiguration
+	if err := json.Unmarshal(data, &current); err != nil {
+		return err
+	}
+
+	var legacy legacyIdeConfiguration
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+
+	*ideConfiguration = IdeConfiguration{DefaultIDE: current.DefaultIDE, DefaultIDEAlternative: current.DefaultIDEAlternative}
+	for languageName, ides := range current.Languages {
+		ideConfiguration.setLanguage(languageName, ides)
+	}
+	for languageName, ides := range legacy.languages() {
+		if _, configured := ideConfiguration.Languages[languageName]; !configured && (ides.Primary != "" || ides.Alternative != "") {
+			ideConfiguration.setLanguage(languageName, ides)
+		}
+	}
+	return nil
+}
+
+// IDE returns the primary IDE of the first of languages that has one, or the default IDE
+func (ideConfiguration IdeConfiguration) IDE(languages ...string) string {
+	for _, languageName := range languages {
+		if ide := ideConfiguration.language(languageName).Primary; ide != "" {
+			return ide
+		}
+	}
+	return ideConfiguration.DefaultIDE
+}
+
+// AlternativeIDE returns the alternative IDE of the first of languages that has one, or the default alternative IDE,
+// or the default IDE
+func (ideConfiguration IdeConfiguration) AlternativeIDE(languages ...string) string {
+	for _, languageName := range languages {
+		if ide := ideConfiguration.language(languageName).Alternative; ide != "" {
+			return ide
+		}
+	}
+	return ifEmpty(ideConfiguration.DefaultIDEAlternative, ideConfiguration.DefaultIDE)
+}
+
+// IDEs returns all the IDEs that may open a project of the given languages: its IDE and alternative IDE, followed by
+// the IDEs of each language
+func (ideConfiguration IdeConfiguration) IDEs(languages ...string) []string {
+	candidates := []string{ideConfiguration.IDE(languages...), ideConfiguration.AlternativeIDE(languages...)}
+	for _, languageName := range languages {
+		ides := ideConfiguration.language(languageName)
+		candidates = append(append(candidates, ides.Primary, ides.Alternative), ides.Additional...)
+	}
+
+	var ides []string
+	for _, ide := range candidates {
+		if ide != "" && !slices.Contains(ides, ide) {
+			ides = append(ides, ide)
+		}
+	}
+	return ides
+}
+
+// SortedLanguages returns the configured languages in alphabetical order
+func (ideConfiguration IdeConfiguration) SortedLanguages() []string {
+	var languages []string
+	for languageName := range ideConfiguration.Languages {
+		languages = append(languages, languageName)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// language returns the IDEs of languageName, whatever its case
+func (ideConfiguration IdeConfiguration) language(languageName string) LanguageIDEs {
+	return ideConfiguration.Languages[strings.ToLower(languageName)]
+}
+
+func (ideConfiguration *IdeConfiguration) setLanguage(languageName string, ides LanguageIDEs) {
+	if ideConfiguration.Languages == nil {
+		ideConfiguration.Languages = map[string]LanguageIDEs{}
+	}
+	ideConfiguration.Languages[strings.ToLower(languageName)] = ides
+}
+
+func ifEmpty(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
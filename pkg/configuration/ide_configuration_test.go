@@ -0,0 +1,108 @@
+package configuration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIdeConfiguration_LegacyFields(t *testing.T) {
+	directory := t.TempDir()
+	legacyConfig := `{
+		"ideConfiguration": {
+			"default": "code",
+			"go": "goland",
+			"goAlternative": "zed",
+			"java": "idea",
+			"languages": {"java": {"primary": "eclipse"}}
+		}
+	}`
+	if err := os.WriteFile(filepath.Join(directory, "config.json"), []byte(legacyConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	configuration, err := LoadConfigurationFrom(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ideConfiguration := configuration.UserConfiguration.IdeConfiguration
+	expected := map[string]LanguageIDEs{
+		"go":   {Primary: "goland", Alternative: "zed"},
+		"java": {Primary: "eclipse"},
+	}
+	if !reflect.DeepEqual(ideConfiguration.Languages, expected) {
+		t.Errorf("Expected the legacy fields to be read into %v, got %v", expected, ideConfiguration.Languages)
+	}
+
+	saved, _ := json.Marshal(ideConfiguration)
+	if strings.Contains(string(saved), `"go":"goland"`) {
+		t.Errorf("Expected the legacy fields not to be written back: %s", saved)
+	}
+}
+
+func TestIdeConfiguration_Lookup(t *testing.T) {
+	ideConfiguration := IdeConfiguration{
+		DefaultIDE: "code",
+		Languages: map[string]LanguageIDEs{
+			"csharp": {Primary: "rider", Additional: []string{"code", "vim"}},
+			"ruby":   {Alternative: "rubymine"},
+		},
+	}
+
+	for _, test := range []struct {
+		languages   []string
+		ide         string
+		alternative string
+		ides        []string
+	}{
+		{[]string{"csharp"}, "rider", "code", []string{"rider", "code", "vim"}},
+		{[]string{"ruby"}, "code", "rubymine", []string{"code", "rubymine"}},
+		{[]string{"ruby", "csharp"}, "rider", "rubymine", []string{"rider", "rubymine", "code", "vim"}},
+		{nil, "code", "code", []string{"code"}},
+	} {
+		if ide := ideConfiguration.IDE(test.languages...); ide != test.ide {
+			t.Errorf("Expected %q for %q, got %q", test.ide, test.languages, ide)
+		}
+		if alternative := ideConfiguration.AlternativeIDE(test.languages...); alternative != test.alternative {
+			t.Errorf("Expected alternative %q for %q, got %q", test.alternative, test.languages, alternative)
+		}
+		if ides := ideConfiguration.IDEs(test.languages...); !reflect.DeepEqual(ides, test.ides) {
+			t.Errorf("Expected IDEs %v for %q, got %v", test.ides, test.languages, ides)
+		}
+	}
+}
+
+func TestIdeConfiguration_LanguageCase(t *testing.T) {
+	var ideConfiguration IdeConfiguration
+	if err := json.Unmarshal([]byte(`{"default": "code", "languages": {"Zig": {"primary": "zed"}}}`), &ideConfiguration); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, languageName := range []string{"zig", "Zig", "ZIG"} {
+		if ide := ideConfiguration.IDE(languageName); ide != "zed" {
+			t.Errorf("Expected zed for %s, got %s", languageName, ide)
+		}
+	}
+}
+
+func TestLanguageIDEFlag(t *testing.T) {
+	var languageIDEs LanguageIDEFlag
+	for _, value := range []string{"scala=idea", "cpp=clion --wait"} {
+		if err := languageIDEs.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if languageIDEs.String() != "scala=idea,cpp=clion --wait" {
+		t.Errorf("Unexpected flag values: %s", languageIDEs.String())
+	}
+
+	for _, value := range []string{"idea", "=idea", "scala="} {
+		if err := languageIDEs.Set(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
@@ -1,32 +1,98 @@
 package csv
 
 import (
+	"bufio"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"strconv"
+	"strings"
 )
 
+// Index files start with a version line followed by a header naming the columns:
+//
+//	#griffin-index;2
+//	baseDir;name;type
+//	/home/me/src;griffin;go
+//
+// Files written before the format was versioned have neither and are read as version 1,
+// using the schema's LegacyColumns to name their columns by position.
+const VERSION_LINE_PREFIX = "#griffin-index"
+
+const LEGACY_VERSION = 1
+
+// MissingIndexError is returned when an index was not built yet.
+type MissingIndexError struct {
+	Path string
+}
+
+func (err *MissingIndexError) Error() string {
+	return fmt.Sprintf("index %s does not exist", err.Path)
+}
+
+// CorruptIndexError is returned when an index cannot be read, e.g. because it is malformed or was written by a newer
+// griffin version.
+type CorruptIndexError struct {
+	Path string
+	Err  error
+}
+
+func (err *CorruptIndexError) Error() string {
+	return fmt.Sprintf("index %s cannot be read: %v", err.Path, err.Err)
+}
+
+func (err *CorruptIndexError) Unwrap() error {
+	return err.Err
+}
+
 type CsvData interface {
+	// AsCsvRecord returns the values of the schema's columns, in order
 	AsCsvRecord() []string
 }
 
-func SaveIndex[T CsvData](filePath string, data []T) error {
+// Schema describes the columns of an index.
+// Columns can be added or reordered freely - they are read by name, and missing ones read as empty.
+// Bump Version when the meaning of existing columns changes, so older griffin versions refuse the file.
+type Schema struct {
+	Version       int
+	Columns       []string
+	LegacyColumns []string
+}
+
+// IndexRecord is a row of an index, by column name.
+type IndexRecord map[string]string
+
+// Get returns the value of a column, or "" if the index has no such column.
+func (record IndexRecord) Get(column string) string {
+	return record[column]
+}
+
+func SaveIndex[T CsvData](filePath string, schema Schema, data []T) error {
 	file, err := os.Create(filePath + ".new")
 	if err != nil {
-		fmt.Println("Error creating CSV file:", err)
-		return err
+		return fmt.Errorf("error creating %s: %w", filePath+".new", err)
 	}
 	defer file.Close()
 
 	writer := csv.NewWriter(file)
 	writer.Comma = ';'
 
+	// Write header
+	if err := writer.Write([]string{VERSION_LINE_PREFIX, strconv.Itoa(schema.Version)}); err != nil {
+		return fmt.Errorf("error writing the header of %s: %w", filePath, err)
+	}
+	if err := writer.Write(schema.Columns); err != nil {
+		return fmt.Errorf("error writing the header of %s: %w", filePath, err)
+	}
+
 	// Write data
 	for _, datum := range data {
 		err := writer.Write(datum.AsCsvRecord())
 		if err != nil {
-			fmt.Println("Error writing CSV row:", err)
-			return err
+			return fmt.Errorf("error writing a row of %s: %w", filePath, err)
 		}
 	}
 
@@ -34,52 +100,133 @@ func SaveIndex[T CsvData](filePath string, data []T) error {
 
 	// Check for errors during Flush
 	if err := writer.Error(); err != nil {
-		fmt.Println("Error flushing CSV writer:", err)
-		return err
+		return fmt.Errorf("error writing %s: %w", filePath, err)
 	}
 
-	os.Rename(filePath+".new", filePath)
-
-	return nil
+	return os.Rename(filePath+".new", filePath)
 }
 
-type ConverterFunc[T CsvData] func([]string) (T, error)
+type ConverterFunc[T CsvData] func(IndexRecord) (T, error)
 
-func LoadIndex[T CsvData](filePath string, converter ConverterFunc[T]) []T {
+// LoadIndex reads an index written with any version up to the schema's, and rewrites older
+// files in the current format. All their rows are rewritten, including the ones converter skips - a rewrite that
+// fails is reported to warn, as the index can still be read.
+// It fails with a *MissingIndexError if the index does not exist and a *CorruptIndexError if it cannot be read.
+func LoadIndex[T CsvData](filePath string, schema Schema, converter ConverterFunc[T], warn func(format string, args ...any)) ([]T, error) {
 
-	rawCsv, error := loadCsv(filePath)
+	version, records, error := loadCsv(filePath, schema)
 
+	if errors.Is(error, fs.ErrNotExist) {
+		return nil, &MissingIndexError{Path: filePath}
+	}
 	if error != nil {
-		fmt.Println("Could not load data:", error.Error())
-		os.Exit(1)
+		return nil, &CorruptIndexError{Path: filePath, Err: error}
 	}
 
 	var items []T
 
-	for _, line := range rawCsv {
-		data, error := converter(line)
+	for _, record := range records {
+		data, error := converter(record)
 		if error == nil {
 			items = append(items, data)
 		}
 	}
 
-	return items
+	if version < schema.Version {
+		if err := SaveIndex(filePath, schema, migratedRecords(records, schema)); err != nil {
+			warn("Could not migrate index %s to version %d: %v", filePath, schema.Version, err)
+		}
+	}
+
+	return items, nil
+}
+
+// migratedRecord is a row of an older index, written with the schema's columns
+type migratedRecord struct {
+	record  IndexRecord
+	columns []string
+}
+
+func (migrated migratedRecord) AsCsvRecord() []string {
+	values := make([]string, len(migrated.columns))
+	for i, column := range migrated.columns {
+		values[i] = migrated.record.Get(column)
+	}
+	return values
+}
+
+func migratedRecords(records []IndexRecord, schema Schema) []migratedRecord {
+	migrated := make([]migratedRecord, len(records))
+	for i, record := range records {
+		migrated[i] = migratedRecord{record: record, columns: schema.Columns}
+	}
+	return migrated
 }
 
-func loadCsv(filePath string) ([][]string, error) {
+func loadCsv(filePath string, schema Schema) (int, []IndexRecord, error) {
 	file, fileOpenError := os.Open(filePath)
 	if fileOpenError != nil {
-		return nil, fileOpenError
+		return 0, nil, fileOpenError
 	}
 
 	defer file.Close()
 
-	csvReader := csv.NewReader(file)
+	reader := bufio.NewReader(file)
+	version, columns, headerError := readHeader(reader, schema)
+	if headerError != nil {
+		return 0, nil, headerError
+	}
+
+	csvReader := csv.NewReader(reader)
 	csvReader.Comma = ';'
+	csvReader.FieldsPerRecord = -1
 	data, csvReadError := csvReader.ReadAll()
 	if csvReadError != nil {
-		return nil, csvReadError
+		return 0, nil, csvReadError
+	}
+
+	var records []IndexRecord
+	for _, row := range data {
+		record := IndexRecord{}
+		for i, value := range row {
+			if i < len(columns) {
+				record[columns[i]] = value
+			}
+		}
+		records = append(records, record)
+	}
+
+	return version, records, nil
+}
+
+// readHeader returns the version and column names of an index, consuming its header lines.
+func readHeader(reader *bufio.Reader, schema Schema) (int, []string, error) {
+	firstLine, err := reader.Peek(len(VERSION_LINE_PREFIX))
+	if err == io.EOF || (err == nil && string(firstLine) != VERSION_LINE_PREFIX) {
+		return LEGACY_VERSION, schema.LegacyColumns, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	versionLine, err := reader.ReadString('\n')
+	if err != nil {
+		return 0, nil, fmt.Errorf("missing index header")
+	}
+	_, versionText, _ := strings.Cut(strings.TrimSpace(versionLine), ";")
+	version, err := strconv.Atoi(versionText)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid index version: %s", versionText)
+	}
+	if version > schema.Version {
+		return 0, nil, fmt.Errorf("index version %d is newer than the supported version %d - rebuild the index or upgrade griffin", version, schema.Version)
+	}
+
+	columnsLine, err := reader.ReadString('\n')
+	if err != nil && columnsLine == "" {
+		return 0, nil, fmt.Errorf("missing index columns")
 	}
+	columns := strings.Split(strings.TrimSpace(columnsLine), ";")
 
-	return data, nil
+	return version, columns, nil
 }
@@ -0,0 +1,143 @@
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	Name  string
+	Color string
+}
+
+func (datum item) AsCsvRecord() []string {
+	return []string{datum.Name, datum.Color}
+}
+
+func itemFromRecord(record IndexRecord) (item, error) {
+	return item{Name: record.Get("name"), Color: record.Get("color")}, nil
+}
+
+var testSchema = Schema{Version: 2, Columns: []string{"name", "color"}, LegacyColumns: []string{"name"}}
+
+func TestSaveAndLoadIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.list")
+	items := []item{{Name: "apple", Color: "red"}, {Name: "lime", Color: "green"}}
+
+	if err := SaveIndex(path, testSchema, items); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(content), "#griffin-index;2\nname;color\n") {
+		t.Errorf("Expected a versioned header, got %q", content)
+	}
+
+	if loaded, err := LoadIndex(path, testSchema, itemFromRecord, t.Logf); err != nil || !reflect.DeepEqual(loaded, items) {
+		t.Errorf("Expected %v, got %v", items, loaded)
+	}
+}
+
+func TestSaveIndex_ReturnsErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "items.list")
+	if err := SaveIndex(path, testSchema, []item{{Name: "apple"}}); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected an error naming %s, got %v", path, err)
+	}
+}
+
+func TestLoadIndex_MigratesLegacyIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.list")
+	if err := os.WriteFile(path, []byte("apple\nlime\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []item{{Name: "apple"}, {Name: "lime"}}
+	if loaded, err := LoadIndex(path, testSchema, itemFromRecord, t.Logf); err != nil || !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("Expected %v, got %v", expected, loaded)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "#griffin-index;2\nname;color\napple;\nlime;\n" {
+		t.Errorf("Expected the index to be rewritten in the current format, got %q", content)
+	}
+}
+
+func TestLoadIndex_MigrationKeepsSkippedRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.list")
+	if err := os.WriteFile(path, []byte("apple\nlime\nplum\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	onlyApples := func(record IndexRecord) (item, error) {
+		if record.Get("name") != "apple" {
+			return item{}, errors.New("skipped")
+		}
+		return itemFromRecord(record)
+	}
+	if loaded, err := LoadIndex(path, testSchema, onlyApples, t.Logf); err != nil || !reflect.DeepEqual(loaded, []item{{Name: "apple"}}) {
+		t.Errorf("Expected only the apple, got %v (%v)", loaded, err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "#griffin-index;2\nname;color\napple;\nlime;\nplum;\n" {
+		t.Errorf("Expected every row to be migrated, got %q", content)
+	}
+}
+
+func TestLoadIndex_ReportsFailedMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.list")
+	if err := os.WriteFile(path, []byte("apple\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The rewrite cannot create its temporary file
+	if err := os.Mkdir(path+".new", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings []string
+	warn := func(format string, args ...any) { warnings = append(warnings, fmt.Sprintf(format, args...)) }
+	if loaded, err := LoadIndex(path, testSchema, itemFromRecord, warn); err != nil || !reflect.DeepEqual(loaded, []item{{Name: "apple"}}) {
+		t.Errorf("Expected the index to be read despite the failed migration, got %v (%v)", loaded, err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], path) {
+		t.Errorf("Expected the failed migration to be reported, got %v", warnings)
+	}
+}
+
+func TestLoadIndex_ReadsColumnsByName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.list")
+	if err := os.WriteFile(path, []byte("#griffin-index;2\ncolor;shape;name\nred;round;apple\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []item{{Name: "apple", Color: "red"}}
+	if loaded, err := LoadIndex(path, testSchema, itemFromRecord, t.Logf); err != nil || !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("Expected %v, got %v", expected, loaded)
+	}
+}
+
+func TestLoadIndex_RejectsNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.list")
+	if err := os.WriteFile(path, []byte("#griffin-index;3\nname;color\napple;red\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadIndex(path, testSchema, itemFromRecord, t.Logf)
+	var corruptIndexError *CorruptIndexError
+	if !errors.As(err, &corruptIndexError) || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected a CorruptIndexError for a newer index version, got %v", err)
+	}
+}
+
+func TestLoadIndex_MissingIndex(t *testing.T) {
+	_, err := LoadIndex(filepath.Join(t.TempDir(), "missing.list"), testSchema, itemFromRecord, t.Logf)
+	var missingIndexError *MissingIndexError
+	if !errors.As(err, &missingIndexError) {
+		t.Errorf("Expected a MissingIndexError, got %v", err)
+	}
+}
@@ -2,50 +2,176 @@ package finder
 
 import (
 	"fmt"
+	"os"
+	"reflect"
+	"strings"
 
 	alfred "ronkitay.com/griffin/pkg/alfred"
+	"ronkitay.com/griffin/pkg/griffin"
 	matcher "ronkitay.com/griffin/pkg/matcher"
-	projectIndex "ronkitay.com/griffin/pkg/projectindex"
 	repoIndex "ronkitay.com/griffin/pkg/repoindex"
 )
 
-func FindRepo(executableName string, noArchives bool, noDirs bool, alfredOutput bool, args []string) {
-	allRepos := repoIndex.LoadIndex(noArchives, noDirs)
+// SearchOptions control how find-repo and find-project match and print results
+type SearchOptions struct {
+	griffin.SearchOptions
+	AlfredOutput bool
+	// Format is one of SUPPORTED_FORMATS or a text/template executed per result, printing paths when empty
+	Format     string
+	ShowScores bool
+}
+
+type RepoSearchOptions struct {
+	SearchOptions
+	NoArchives   bool
+	NoDirs       bool
+	MatchRemotes bool
+	ShowRemotes  bool
+}
+
+// InvalidFormatError is returned for an unsupported format, or a template that does not parse or refers to fields
+// the records do not have.
+type InvalidFormatError struct {
+	Format string
+	Err    error
+}
 
-	regexPattern := matcher.BuildPattern(args)
+func (err *InvalidFormatError) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf("invalid format template: %v", err.Err)
+	}
+	return fmt.Sprintf("unsupported format '%s' (supported formats: %s)", err.Format, strings.Join(SUPPORTED_FORMATS, ", "))
+}
 
-	matchingRepos := matcher.MatchItems(allRepos, regexPattern)
+func (err *InvalidFormatError) Unwrap() error {
+	return err.Err
+}
 
-	if alfredOutput {
-		result := alfred.ReposAsAlfred(matchingRepos)
+func FindRepo(client *griffin.Client, options RepoSearchOptions, args []string) error {
+	if err := validateFormat[RepoRecord](options.SearchOptions); err != nil {
+		return err
+	}
+
+	matchingRepos, err := client.FindRepos(griffin.RepoSearchOptions{
+		SearchOptions: options.SearchOptions.SearchOptions,
+		NoArchives:    options.NoArchives,
+		NoDirs:        options.NoDirs,
+		MatchRemotes:  options.MatchRemotes,
+	}, args)
+	if err != nil {
+		return err
+	}
+
+	switch outputFormat(options.SearchOptions) {
+	case FORMAT_ALFRED:
+		result, err := alfred.ReposAsAlfred(items(matchingRepos))
+		if err != nil {
+			return err
+		}
 		fmt.Println(result)
-	} else {
-		printPaths(matchingRepos)
+	case FORMAT_PATHS:
+		var details func(repoIndex.RepoData) string
+		if options.ShowRemotes {
+			details = func(repo repoIndex.RepoData) string { return remotesAsText(repo.Remotes) }
+		}
+		printPaths(matchingRepos, options.ShowScores, details)
+	default:
+		var records []RepoRecord
+		for _, repo := range matchingRepos {
+			records = append(records, NewRepoRecord(repo))
+		}
+		return printRecords(os.Stdout, records, options.Format)
+	}
+	return nil
+}
+
+func remotesAsText(remotes []repoIndex.Remote) string {
+	var pairs []string
+	for _, remote := range remotes {
+		pairs = append(pairs, remote.Name+"="+remote.Url)
 	}
+	return strings.Join(pairs, " ")
+}
+
+func outputFormat(options SearchOptions) string {
+	if options.AlfredOutput {
+		return FORMAT_ALFRED
+	}
+	if options.Format == "" {
+		return FORMAT_PATHS
+	}
+	return options.Format
+}
+
+// validateFormat checks the format before anything is printed - a template fails when it refers to a field R does
+// not have
+func validateFormat[R outputRecord](options SearchOptions) error {
+	if isTemplateFormat(options.Format) {
+		tmpl, err := parseTemplate(options.Format)
+		if err != nil {
+			return &InvalidFormatError{Format: options.Format, Err: err}
+		}
+		recordType := reflect.TypeFor[R]()
+		if field := unknownField(tmpl.Tree.Root, recordType, recordType); field != "" {
+			return &InvalidFormatError{Format: options.Format, Err: fmt.Errorf("%s has no field %s", recordType.Name(), field)}
+		}
+	} else if !isSupportedFormat(outputFormat(options)) {
+		return &InvalidFormatError{Format: options.Format}
+	}
+	return nil
+}
+
+func items[T any](rankedItems []matcher.Ranked[T]) []T {
+	var result []T
+	for _, rankedItem := range rankedItems {
+		result = append(result, rankedItem.Item)
+	}
+	return result
 }
 
 type Printable interface {
 	ToString() string
 }
 
-func printPaths[T Printable](matchingItems []T) {
+// printPaths prints a line per item - its score (if requested), its path and its details (if any)
+func printPaths[T Printable](matchingItems []matcher.Ranked[T], showScores bool, details func(T) string) {
 	for _, item := range matchingItems {
-		fmt.Println(item.ToString())
+		line := item.Item.ToString()
+		if details != nil {
+			line += "\t" + details(item.Item)
+		}
+		if showScores {
+			line = fmt.Sprintf("%d\t%s", item.Score, line)
+		}
+		fmt.Println(line)
 	}
 }
 
-func FindProjects(executableName string, alfredOutput bool, args []string) {
-	allProjects := projectIndex.LoadIndex()
-
-	regexPattern := matcher.BuildPattern(args)
+func FindProjects(client *griffin.Client, options SearchOptions, args []string) error {
+	if err := validateFormat[ProjectRecord](options); err != nil {
+		return err
+	}
 
-	matchingProjects := matcher.MatchItems(allProjects, regexPattern)
+	matchingProjects, err := client.FindProjects(options.SearchOptions, args)
+	if err != nil {
+		return err
+	}
 
-	if alfredOutput {
-		result := alfred.ProjectsAsAlfred(matchingProjects)
+	switch outputFormat(options) {
+	case FORMAT_ALFRED:
+		result, err := alfred.ProjectsAsAlfred(items(matchingProjects))
+		if err != nil {
+			return err
+		}
 		fmt.Println(result)
-	} else {
-		printPaths(matchingProjects)
+	case FORMAT_PATHS:
+		printPaths(matchingProjects, options.ShowScores, nil)
+	default:
+		var records []ProjectRecord
+		for _, project := range matchingProjects {
+			records = append(records, NewProjectRecord(project))
+		}
+		return printRecords(os.Stdout, records, options.Format)
 	}
-
+	return nil
 }
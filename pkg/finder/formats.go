@@ -0,0 +1,352 @@
+package finder
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	matcher "ronkitay.com/griffin/pkg/matcher"
+	projectIndex "ronkitay.com/griffin/pkg/projectindex"
+	repoIndex "ronkitay.com/griffin/pkg/repoindex"
+	"ronkitay.com/griffin/pkg/terminal"
+)
+
+const (
+	FORMAT_PATHS  = "paths"
+	FORMAT_ALFRED = "alfred"
+	FORMAT_JSON   = "json"
+	FORMAT_NDJSON = "ndjson"
+	FORMAT_CSV    = "csv"
+	FORMAT_TSV    = "tsv"
+)
+
+var SUPPORTED_FORMATS = []string{FORMAT_PATHS, FORMAT_ALFRED, FORMAT_JSON, FORMAT_NDJSON, FORMAT_CSV, FORMAT_TSV}
+
+// outputRecord is a search result with all of its fields, as printed by the structured formats
+type outputRecord interface {
+	columns() []string
+	values() []string
+}
+
+type RemoteRecord struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+// RepoRecord is a repository search result, as printed by the structured formats and returned by griffin serve
+type RepoRecord struct {
+	Path     string         `json:"path"`
+	BaseDir  string         `json:"baseDir"`
+	FullName string         `json:"name"`
+	Url      string         `json:"url"`
+	Type     string         `json:"type"`
+	Alias    string         `json:"alias"`
+	Remotes  []RemoteRecord `json:"remotes"`
+	Score    int            `json:"score"`
+}
+
+func NewRepoRecord(repo matcher.Ranked[repoIndex.RepoData]) RepoRecord {
+	remotes := []RemoteRecord{}
+	for _, remote := range repo.Item.Remotes {
+		remotes = append(remotes, RemoteRecord{Name: remote.Name, Url: remote.Url})
+	}
+
+	return RepoRecord{
+		Path:     repo.Item.ToString(),
+		BaseDir:  repo.Item.BaseDir,
+		FullName: repo.Item.FullName,
+		Url:      repo.Item.Url,
+		Type:     repo.Item.Type,
+		Alias:    repo.Item.Alias,
+		Remotes:  remotes,
+		Score:    repo.Score,
+	}
+}
+
+func (record RepoRecord) columns() []string {
+	return []string{"path", "baseDir", "name", "url", "type", "alias", "remotes", "score"}
+}
+
+func (record RepoRecord) values() []string {
+	var remotes []string
+	for _, remote := range record.Remotes {
+		remotes = append(remotes, remote.Name+"="+remote.Url)
+	}
+	return []string{record.Path, record.BaseDir, record.FullName, record.Url, record.Type, record.Alias, strings.Join(remotes, " "), strconv.Itoa(record.Score)}
+}
+
+// ProjectRecord is a project search result, as printed by the structured formats and returned by griffin serve
+type ProjectRecord struct {
+	Path     string `json:"path"`
+	BaseDir  string `json:"baseDir"`
+	FullName string `json:"name"`
+	Language string `json:"language"`
+	// Languages are all the languages of the project, starting with Language
+	Languages []string `json:"languages"`
+	Score     int      `json:"score"`
+}
+
+func NewProjectRecord(project matcher.Ranked[projectIndex.ProjectData]) ProjectRecord {
+	languages := append([]string{}, project.Item.Languages...)
+
+	return ProjectRecord{
+		Path:      project.Item.ToString(),
+		BaseDir:   project.Item.BaseDir,
+		FullName:  project.Item.FullName,
+		Language:  project.Item.Type,
+		Languages: languages,
+		Score:     project.Score,
+	}
+}
+
+func (record ProjectRecord) columns() []string {
+	return []string{"path", "baseDir", "name", "language", "languages", "score"}
+}
+
+func (record ProjectRecord) values() []string {
+	return []string{record.Path, record.BaseDir, record.FullName, record.Language, strings.Join(record.Languages, " "), strconv.Itoa(record.Score)}
+}
+
+// isTemplateFormat tells whether format is a text/template (e.g. `{{.FullName}}\t{{.Url}}`) rather than a named format
+func isTemplateFormat(format string) bool {
+	return strings.Contains(format, "{{")
+}
+
+var TEMPLATE_ESCAPES = strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\\`, `\`)
+
+var TEMPLATE_FUNCTIONS = template.FuncMap{
+	// rel returns path relative to the working directory
+	"rel": func(path string) string {
+		workingDir, err := os.Getwd()
+		if err != nil {
+			return path
+		}
+		return relativePath(workingDir, path)
+	},
+	// relTo returns path relative to base
+	"relTo": relativePath,
+	// shortUrl drops the scheme and .git suffix of url, e.g. github.com/acme/api
+	"shortUrl": func(url string) string {
+		if _, withoutScheme, found := strings.Cut(url, "://"); found {
+			url = withoutScheme
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	},
+	// color wraps text with the escape codes of a color from terminal.COLORS_BY_NAME
+	"color": func(color string, text string) (string, error) {
+		code, found := terminal.COLORS_BY_NAME[color]
+		if !found {
+			return "", fmt.Errorf("unknown color: %s", color)
+		}
+		return code + text + terminal.RESET_COLORS, nil
+	},
+	"base": filepath.Base,
+	"dir":  filepath.Dir,
+}
+
+func relativePath(base string, path string) string {
+	relative, err := filepath.Rel(base, path)
+	if err != nil {
+		return path
+	}
+	return relative
+}
+
+// parseTemplate parses a template format, interpreting \t and \n so they can be typed in a shell
+func parseTemplate(format string) (*template.Template, error) {
+	return template.New("format").Funcs(TEMPLATE_FUNCTIONS).Option("missingkey=error").Parse(TEMPLATE_ESCAPES.Replace(format))
+}
+
+// unknownField finds a field the template refers to that its data (of type dot) does not have, without executing it.
+// Fields of values whose type is not known (results of functions, variables) are not checked.
+func unknownField(node parse.Node, root reflect.Type, dot reflect.Type) string {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return ""
+		}
+		for _, child := range node.Nodes {
+			if field := unknownField(child, root, dot); field != "" {
+				return field
+			}
+		}
+	case *parse.ActionNode:
+		return unknownField(node.Pipe, root, dot)
+	case *parse.TemplateNode:
+		return unknownField(node.Pipe, root, dot)
+	case *parse.IfNode:
+		return unknownBranchField(&node.BranchNode, root, dot, dot)
+	case *parse.RangeNode:
+		return unknownBranchField(&node.BranchNode, root, dot, elementType(pipeType(node.Pipe, root, dot)))
+	case *parse.WithNode:
+		return unknownBranchField(&node.BranchNode, root, dot, pipeType(node.Pipe, root, dot))
+	case *parse.PipeNode:
+		if node == nil {
+			return ""
+		}
+		for _, command := range node.Cmds {
+			if field := unknownField(command, root, dot); field != "" {
+				return field
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range node.Args {
+			if field := unknownField(arg, root, dot); field != "" {
+				return field
+			}
+		}
+	case *parse.ChainNode:
+		return unknownField(node.Node, root, dot)
+	case *parse.FieldNode:
+		_, field := fieldType(dot, node.Ident)
+		return field
+	case *parse.VariableNode:
+		if node.Ident[0] == "$" {
+			_, field := fieldType(root, node.Ident[1:])
+			return field
+		}
+	}
+	return ""
+}
+
+// unknownBranchField finds an unknown field of an if, range or with - whose body is executed with bodyDot
+func unknownBranchField(node *parse.BranchNode, root reflect.Type, dot reflect.Type, bodyDot reflect.Type) string {
+	for _, field := range []string{unknownField(node.Pipe, root, dot), unknownField(node.List, root, bodyDot), unknownField(node.ElseList, root, dot)} {
+		if field != "" {
+			return field
+		}
+	}
+	return ""
+}
+
+// pipeType is the type of a pipeline that is a field of dot (or dot itself), and nil for any other pipeline
+func pipeType(pipe *parse.PipeNode, root reflect.Type, dot reflect.Type) reflect.Type {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return nil
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		fieldType, _ := fieldType(dot, arg.Ident)
+		return fieldType
+	case *parse.VariableNode:
+		if arg.Ident[0] == "$" {
+			fieldType, _ := fieldType(root, arg.Ident[1:])
+			return fieldType
+		}
+	}
+	return nil
+}
+
+// fieldType follows the fields of idents from t, returning their type - or the first of them t does not have
+func fieldType(t reflect.Type, idents []string) (reflect.Type, string) {
+	for _, ident := range idents {
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return nil, ""
+		}
+		if method, found := reflect.PointerTo(t).MethodByName(ident); found {
+			t = nil
+			if method.Type.NumOut() > 0 {
+				t = method.Type.Out(0)
+			}
+			continue
+		}
+		field, found := t.FieldByName(ident)
+		if !found || !field.IsExported() {
+			return nil, ident
+		}
+		t = field.Type
+	}
+	return t, ""
+}
+
+// elementType is the type of the elements of a slice or array, and nil for any other type
+func elementType(t reflect.Type) reflect.Type {
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		return t.Elem()
+	}
+	return nil
+}
+
+func isSupportedFormat(format string) bool {
+	for _, supportedFormat := range SUPPORTED_FORMATS {
+		if format == supportedFormat {
+			return true
+		}
+	}
+	return false
+}
+
+// printRecords prints the records in one of the structured formats (json, ndjson, csv or tsv),
+// or as a line per record using a template format
+func printRecords[R outputRecord](writer io.Writer, records []R, format string) error {
+	if isTemplateFormat(format) {
+		return printTemplate(writer, records, format)
+	}
+
+	switch format {
+	case FORMAT_JSON:
+		if records == nil {
+			records = []R{}
+		}
+		jsonData, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(writer, string(jsonData))
+		return err
+	case FORMAT_NDJSON:
+		encoder := json.NewEncoder(writer)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FORMAT_CSV, FORMAT_TSV:
+		csvWriter := csv.NewWriter(writer)
+		if format == FORMAT_TSV {
+			csvWriter.Comma = '\t'
+		}
+		var header R
+		csvWriter.Write(header.columns())
+		for _, record := range records {
+			csvWriter.Write(record.values())
+		}
+		csvWriter.Flush()
+		return csvWriter.Error()
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func printTemplate[R outputRecord](writer io.Writer, records []R, format string) error {
+	tmpl, err := parseTemplate(format)
+	if err != nil {
+		return err
+	}
+
+	// Every line is rendered before printing, so a failing record does not leave partial output behind
+	var output strings.Builder
+	for _, record := range records {
+		var line strings.Builder
+		if err := tmpl.Execute(&line, record); err != nil {
+			return err
+		}
+		output.WriteString(strings.TrimSuffix(line.String(), "\n") + "\n")
+	}
+	_, err = io.WriteString(writer, output.String())
+	return err
+}
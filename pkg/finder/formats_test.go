@@ -0,0 +1,99 @@
+package finder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	matcher "ronkitay.com/griffin/pkg/matcher"
+	repoIndex "ronkitay.com/griffin/pkg/repoindex"
+)
+
+var testRepo = matcher.Ranked[repoIndex.RepoData]{
+	Item: repoIndex.RepoData{BaseDir: "/src", FullName: "acme/api", Url: "https://github.com/acme/api", Type: "github",
+		Remotes: []repoIndex.Remote{{Name: "origin", Url: "https://github.com/acme/api"}, {Name: "fork", Url: "https://github.com/me/api"}}},
+	Score: 42,
+}
+
+func TestPrintRecords_Delimited(t *testing.T) {
+	var output bytes.Buffer
+	if err := printRecords(&output, []RepoRecord{NewRepoRecord(testRepo)}, FORMAT_TSV); err != nil {
+		t.Fatalf("printRecords failed: %v", err)
+	}
+
+	expected := "path\tbaseDir\tname\turl\ttype\talias\tremotes\tscore\n" +
+		"/src/acme/api\t/src\tacme/api\thttps://github.com/acme/api\tgithub\t\torigin=https://github.com/acme/api fork=https://github.com/me/api\t42\n"
+	if output.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, output.String())
+	}
+}
+
+func TestPrintRecords_NDJSON(t *testing.T) {
+	var output bytes.Buffer
+	records := []RepoRecord{NewRepoRecord(testRepo), NewRepoRecord(testRepo)}
+	if err := printRecords(&output, records, FORMAT_NDJSON); err != nil {
+		t.Fatalf("printRecords failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a line per record, got %q", output.String())
+	}
+
+	var decoded RepoRecord
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %q: %v", lines[0], err)
+	}
+	if decoded.Path != "/src/acme/api" || len(decoded.Remotes) != 2 || decoded.Score != 42 {
+		t.Errorf("Unexpected record: %+v", decoded)
+	}
+}
+
+func TestPrintRecords_EmptyJSON(t *testing.T) {
+	var output bytes.Buffer
+	if err := printRecords[ProjectRecord](&output, nil, FORMAT_JSON); err != nil {
+		t.Fatalf("printRecords failed: %v", err)
+	}
+	if strings.TrimSpace(output.String()) != "[]" {
+		t.Errorf("Expected an empty JSON array, got %q", output.String())
+	}
+}
+
+func TestPrintRecords_Template(t *testing.T) {
+	var output bytes.Buffer
+	format := `{{relTo "/src" .Path}}\t{{shortUrl .Url}}\t{{color "green" .Type}}{{range .Remotes}} {{.Name}}{{end}}\n`
+	if err := printRecords(&output, []RepoRecord{NewRepoRecord(testRepo)}, format); err != nil {
+		t.Fatalf("printRecords failed: %v", err)
+	}
+
+	expected := "acme/api\tgithub.com/acme/api\t\033[32mgithub\033[0m origin fork\n"
+	if output.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, output.String())
+	}
+
+	if err := printRecords(&output, []RepoRecord{NewRepoRecord(testRepo)}, `{{color "pink" .Type}}`); err == nil {
+		t.Errorf("Expected an error for an unknown color")
+	}
+}
+
+func TestValidateFormat_UnknownField(t *testing.T) {
+	var invalidFormatError *InvalidFormatError
+	if err := validateFormat[ProjectRecord](SearchOptions{Format: "{{.Path}} {{.Url}}"}); !errors.As(err, &invalidFormatError) {
+		t.Errorf("Expected a project template using .Url to be rejected, got %v", err)
+	}
+	if err := validateFormat[RepoRecord](SearchOptions{Format: `{{.Path}} {{shortUrl .Url}} {{color "green" .Type}}`}); err != nil {
+		t.Errorf("Expected a repo template using .Url to be accepted, got %v", err)
+	}
+	for _, format := range []string{"{{(index .Remotes 0).Url}}", "{{slice .Path 1}}", "{{range .Remotes}}{{.Name}}={{.Url}} {{end}}"} {
+		if err := validateFormat[RepoRecord](SearchOptions{Format: format}); err != nil {
+			t.Errorf("Expected %q to be accepted, got %v", format, err)
+		}
+	}
+	for _, format := range []string{"{{range .Remotes}}{{.Path}}{{end}}", "{{with .Remotes}}{{$.Nmae}}{{end}}", "{{if .Score}}{{.Scroe}}{{end}}"} {
+		if err := validateFormat[RepoRecord](SearchOptions{Format: format}); !errors.As(err, &invalidFormatError) {
+			t.Errorf("Expected %q to be rejected, got %v", format, err)
+		}
+	}
+}
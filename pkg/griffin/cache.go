@@ -0,0 +1,41 @@
+package griffin
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// cachedFile holds a value loaded from a file, and reloads it only once the file changes.
+type cachedFile[V any] struct {
+	lock    sync.Mutex
+	loaded  bool
+	modTime time.Time
+	size    int64
+	value   V
+}
+
+// get returns the cached value of the file at path, or loads it if the file changed since it was cached.
+// Files that cannot be stat'ed (e.g. missing ones) are never cached, so load decides how to handle them.
+func (cache *cachedFile[V]) get(path string, load func() (V, error)) (V, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return load()
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if cache.loaded && cache.modTime.Equal(info.ModTime()) && cache.size == info.Size() {
+		return cache.value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+
+	// If the file changes while it is loaded, the next call sees a different stat and loads it again
+	cache.loaded, cache.modTime, cache.size, cache.value = true, info.ModTime(), info.Size(), value
+	return value, nil
+}
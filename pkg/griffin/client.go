@@ -0,0 +1,366 @@
+// Package griffin is the library API of griffin: building the repository and project indexes,
+// searching them and opening results in an IDE. Nothing is printed - results and errors are returned, and problems
+// that do not stop indexing are written to the configuration's Warnings, when it is set.
+package griffin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	config "ronkitay.com/griffin/pkg/configuration"
+	history "ronkitay.com/griffin/pkg/history"
+	"ronkitay.com/griffin/pkg/idelauncher"
+	matcher "ronkitay.com/griffin/pkg/matcher"
+	projectIndex "ronkitay.com/griffin/pkg/projectindex"
+	repoIndex "ronkitay.com/griffin/pkg/repoindex"
+)
+
+type Repo = repoIndex.RepoData
+
+type Project = projectIndex.ProjectData
+
+// Client runs griffin against an explicit configuration.
+// The indexes and the usage history are kept in memory, and reloaded when their files change, so a long-lived
+// client answers searches without reading them again. A Client is safe for concurrent use.
+type Client struct {
+	configuration config.Configuration
+	repos         cachedFile[[]Repo]
+	projects      cachedFile[[]Project]
+	usage         cachedFile[*history.History]
+}
+
+// NewClient returns a client using configuration, e.g. one built with config.NewConfiguration.
+func NewClient(configuration config.Configuration) *Client {
+	return &Client{configuration: configuration}
+}
+
+// NewDefaultClient returns a client using the configuration in $HOME/.config/griffin, like the griffin command.
+func NewDefaultClient() (*Client, error) {
+	configuration, err := config.LoadConfiguration()
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(configuration), nil
+}
+
+func (client *Client) Configuration() config.Configuration {
+	return client.configuration
+}
+
+// SearchOptions control how repositories and projects are matched and ordered
+type SearchOptions struct {
+	// Regex matches the filters as a single regular expression (in index order) instead of ranking fuzzy matches
+	Regex bool
+	// NoHistory ignores how frequently and recently results were selected when ordering them
+	NoHistory bool
+}
+
+type RepoSearchOptions struct {
+	SearchOptions
+	NoArchives   bool
+	NoDirs       bool
+	MatchRemotes bool
+}
+
+// BuildRepoIndex builds the repository index, using jobs workers.
+// When incremental is set, only the directories and repositories that changed since the previous build are re-scanned.
+func (client *Client) BuildRepoIndex(jobs int, incremental bool) error {
+	return repoIndex.BuildRepoIndex(client.configuration, jobs, incremental)
+}
+
+// BuildProjectIndex builds the project index from the repositories in the repository index.
+func (client *Client) BuildProjectIndex() error {
+	return projectIndex.BuildProjectIndex(client.configuration)
+}
+
+// Repos returns all indexed repositories, in index order.
+func (client *Client) Repos(noArchives bool, noDirs bool) ([]Repo, error) {
+	allRepos, err := client.repos.get(client.configuration.RepoListLocation, func() ([]Repo, error) {
+		return repoIndex.LoadIndex(client.configuration, false, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []Repo
+	for _, repo := range allRepos {
+		if (noArchives && repo.Type == "archive") || (noDirs && repo.Type == "dir") {
+			continue
+		}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+// Projects returns all indexed projects, in index order.
+func (client *Client) Projects() ([]Project, error) {
+	projects, err := client.projects.get(client.configuration.ProjectListLocation, func() ([]Project, error) {
+		return projectIndex.LoadIndex(client.configuration)
+	})
+	return append([]Project(nil), projects...), err
+}
+
+// RepoOf returns the indexed repository containing path - the innermost one when repositories are nested.
+func (client *Client) RepoOf(path string) (Repo, bool, error) {
+	reposByRoot, err := client.reposByRoot()
+	if err != nil {
+		return Repo{}, false, err
+	}
+
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return Repo{}, false, err
+	}
+	repo, found := innermostRepo(absolutePath, reposByRoot)
+	return repo, found, nil
+}
+
+// ProjectsIn returns the indexed projects of the repository containing repoPath, in index order.
+// Projects of repositories nested in it are not included.
+func (client *Client) ProjectsIn(repoPath string) ([]Project, error) {
+	repo, found, err := client.RepoOf(repoPath)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	reposByRoot, err := client.reposByRoot()
+	if err != nil {
+		return nil, err
+	}
+	projects, err := client.Projects()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Project
+	for _, project := range projects {
+		if owner, found := innermostRepo(project.ToString(), reposByRoot); found && owner.ToString() == repo.ToString() {
+			result = append(result, project)
+		}
+	}
+	return result, nil
+}
+
+// reposByRoot maps the root of every indexed repository (leaving out plain directories) to the repository
+func (client *Client) reposByRoot() (map[string]Repo, error) {
+	repos, err := client.Repos(false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	reposByRoot := make(map[string]Repo)
+	for _, repo := range repos {
+		reposByRoot[repo.ToString()] = repo
+	}
+	return reposByRoot, nil
+}
+
+func innermostRepo(path string, reposByRoot map[string]Repo) (Repo, bool) {
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if repo, found := reposByRoot[dir]; found {
+			return repo, true
+		}
+		if dir == filepath.Dir(dir) {
+			return Repo{}, false
+		}
+	}
+}
+
+// FindRepos returns the repositories matching the query, best match first.
+// The query is made of free text terms and field filters such as `type:gitlab` (see matcher.ParseQuery).
+func (client *Client) FindRepos(options RepoSearchOptions, query []string) ([]matcher.Ranked[Repo], error) {
+	parsedQuery := matcher.ParseQuery(query)
+	indexedRepos, err := client.Repos(options.NoArchives, options.NoDirs)
+	if err != nil {
+		return nil, err
+	}
+	allRepos, err := matcher.FilterItems(indexedRepos, parsedQuery.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var matchingRepos []matcher.Ranked[Repo]
+	if options.MatchRemotes {
+		var matchingRemotes []matcher.Ranked[remoteMatchable]
+		matchingRemotes, err = matchItems(wrapRemoteMatchables(allRepos), options.Regex, parsedQuery.Terms)
+		matchingRepos = unwrapRemoteMatchables(matchingRemotes)
+	} else {
+		matchingRepos, err = matchItems(allRepos, options.Regex, parsedQuery.Terms)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if options.NoHistory {
+		return matchingRepos, nil
+	}
+	usage, err := client.usageHistory()
+	if err != nil {
+		return nil, err
+	}
+	return rankByHistory(usage, matchingRepos), nil
+}
+
+// FindProjects returns the projects matching the query, best match first.
+// The query is made of free text terms and field filters such as `lang:go` (see matcher.ParseQuery).
+func (client *Client) FindProjects(options SearchOptions, query []string) ([]matcher.Ranked[Project], error) {
+	parsedQuery := matcher.ParseQuery(query)
+	indexedProjects, err := client.Projects()
+	if err != nil {
+		return nil, err
+	}
+	allProjects, err := matcher.FilterItems(indexedProjects, parsedQuery.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	matchingProjects, err := matchItems(allProjects, options.Regex, parsedQuery.Terms)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.NoHistory {
+		return matchingProjects, nil
+	}
+	usage, err := client.usageHistory()
+	if err != nil {
+		return nil, err
+	}
+	return rankByHistory(usage, matchingProjects), nil
+}
+
+// Record registers that the paths were selected, to rank them higher in future searches.
+func (client *Client) Record(paths ...string) error {
+	usage, err := history.Load(client.configuration)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		usage.Record(path, time.Now())
+	}
+	return usage.Save()
+}
+
+// OpenInIDE opens path in the IDE configured for its language. A file is opened in its project (see ProjectRootOf).
+func (client *Client) OpenInIDE(path string) error {
+	return client.OpenFileInIDE(path, 0, "", false)
+}
+
+// OpenInAlternativeIDE opens path in the alternative IDE configured for its language.
+func (client *Client) OpenInAlternativeIDE(path string) error {
+	return client.OpenFileInIDE(path, 0, "", true)
+}
+
+// IDEs returns the IDEs configured for the languages of path, starting with the one OpenInIDE uses.
+func (client *Client) IDEs(path string) ([]string, error) {
+	target, err := client.ideTarget(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	return idelauncher.IDEs(client.configuration, target.Path)
+}
+
+// OpenWithIDE opens path in the given IDE.
+func (client *Client) OpenWithIDE(path string, ide string) error {
+	if ide == "" {
+		return idelauncher.ErrMissingDefaultIDE
+	}
+	return client.OpenFileInIDE(path, 0, ide, false)
+}
+
+// OpenFileInIDE opens path - at line, when it is a file and line is positive - in ide, or in the (alternative) IDE
+// configured for its language when ide is empty.
+func (client *Client) OpenFileInIDE(path string, line int, ide string, alternative bool) error {
+	target, err := client.ideTarget(path, line)
+	if err != nil {
+		return err
+	}
+	if ide != "" {
+		return idelauncher.OpenWith(client.configuration, ide, target)
+	}
+	if alternative {
+		return idelauncher.OpenInAlternativeIDE(client.configuration, target)
+	}
+	return idelauncher.OpenInIDE(client.configuration, target)
+}
+
+// ResolveIDE returns the command OpenFileInIDE runs, without running it.
+func (client *Client) ResolveIDE(path string, line int, ide string, alternative bool) (idelauncher.Launch, error) {
+	target, err := client.ideTarget(path, line)
+	if err != nil {
+		return idelauncher.Launch{}, err
+	}
+	return idelauncher.ResolveIDE(client.configuration, ide, target, alternative)
+}
+
+// ProjectRootOf returns the project of file: the nearest directory above it that is a project (see language.Detector)
+// or the root of an indexed repository. It is the directory of the file when there is neither.
+func (client *Client) ProjectRootOf(file string) (string, error) {
+	absolutePath, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	detector, err := client.configuration.LanguageDetector()
+	if err != nil {
+		return "", err
+	}
+	// Without a repository index, only project markers are looked for
+	reposByRoot, _ := client.reposByRoot()
+
+	fileDir := filepath.Dir(absolutePath)
+	for dir := fileDir; ; dir = filepath.Dir(dir) {
+		if _, isProject := detector.Detect(dir); isProject {
+			return dir, nil
+		}
+		if _, isRepo := reposByRoot[dir]; isRepo {
+			return dir, nil
+		}
+		if dir == filepath.Dir(dir) {
+			return fileDir, nil
+		}
+	}
+}
+
+// ideTarget returns path as an IDE target, with the URL of its repository when it is in the index. A file is opened
+// in its project, at line.
+func (client *Client) ideTarget(path string, line int) (idelauncher.Target, error) {
+	target := idelauncher.Target{Path: path}
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		projectRoot, err := client.ProjectRootOf(path)
+		if err != nil {
+			return target, err
+		}
+		absolutePath, _ := filepath.Abs(path)
+		target = idelauncher.Target{Path: projectRoot, File: absolutePath, Line: line}
+	}
+
+	if repo, found, err := client.RepoOf(target.Path); err == nil && found {
+		target.RepoURL = repo.Url
+	}
+	return target, nil
+}
+
+// OpenInBrowser opens the web page of the repository containing path.
+func (client *Client) OpenInBrowser(path string) error {
+	repo, found, err := client.RepoOf(path)
+	if err != nil {
+		return err
+	}
+	if !found || !strings.HasPrefix(repo.Url, "http") {
+		return fmt.Errorf("no web URL for %s", path)
+	}
+
+	opener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		opener = "open"
+	}
+	if err := exec.Command(opener, repo.Url).Start(); err != nil {
+		return fmt.Errorf("error opening %s: %w", repo.Url, err)
+	}
+	return nil
+}
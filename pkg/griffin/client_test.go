@@ -0,0 +1,144 @@
+package griffin
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	config "ronkitay.com/griffin/pkg/configuration"
+	csvHelper "ronkitay.com/griffin/pkg/csv"
+)
+
+func newTestClient(t *testing.T, repos ...string) *Client {
+	root := t.TempDir()
+	for _, repo := range repos {
+		if err := os.MkdirAll(filepath.Join(root, repo), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command("git", "init", "-q", filepath.Join(root, repo)).CombinedOutput(); err != nil {
+			t.Fatalf("git init failed: %v\nOutput: %s", err, out)
+		}
+		if err := os.WriteFile(filepath.Join(root, repo, "go.mod"), []byte("module "+repo), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configuration := config.NewConfiguration(t.TempDir(), config.UserConfiguration{RepoRoots: []string{root}})
+	return NewClient(configuration)
+}
+
+func TestClient_BuildAndFind(t *testing.T) {
+	client := newTestClient(t, "api-gateway", "website")
+
+	if err := client.BuildRepoIndex(2, false); err != nil {
+		t.Fatalf("BuildRepoIndex failed: %v", err)
+	}
+	if err := client.BuildProjectIndex(); err != nil {
+		t.Fatalf("BuildProjectIndex failed: %v", err)
+	}
+
+	repos, err := client.FindRepos(RepoSearchOptions{NoDirs: true}, []string{"gw"})
+	if err != nil {
+		t.Fatalf("FindRepos failed: %v", err)
+	}
+	if len(repos) != 1 || repos[0].Item.FullName != "api-gateway" || repos[0].Item.Type != "local" {
+		t.Errorf("Expected the api-gateway repo, got %v", repos)
+	}
+
+	projects, err := client.FindProjects(SearchOptions{}, []string{"lang:go"})
+	if err != nil {
+		t.Fatalf("FindProjects failed: %v", err)
+	}
+	if len(projects) != 2 {
+		t.Errorf("Expected both projects, got %v", projects)
+	}
+}
+
+func TestClient_PolyglotProjects(t *testing.T) {
+	client := newTestClient(t, "api-gateway", "website")
+	websiteDir := filepath.Join(client.Configuration().UserConfiguration.RepoRoots[0], "website")
+	if err := os.WriteFile(filepath.Join(websiteDir, "package.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.BuildRepoIndex(1, false); err != nil {
+		t.Fatalf("BuildRepoIndex failed: %v", err)
+	}
+	if err := client.BuildProjectIndex(); err != nil {
+		t.Fatalf("BuildProjectIndex failed: %v", err)
+	}
+
+	for query, expected := range map[string]int{"lang:go": 2, "lang:node": 1, "type:node": 0} {
+		projects, err := client.FindProjects(SearchOptions{}, []string{query})
+		if err != nil {
+			t.Fatalf("FindProjects failed: %v", err)
+		}
+		if len(projects) != expected {
+			t.Errorf("Expected %d projects for %s, got %v", expected, query, projects)
+		}
+	}
+
+	projects, _ := client.FindProjects(SearchOptions{}, []string{"lang:node"})
+	if website := projects[0].Item; website.Type != "go" || len(website.Languages) != 2 || website.Languages[1] != "node" {
+		t.Errorf("Expected go to be the primary language of the website, followed by node: %+v", website)
+	}
+}
+
+func TestClient_ProjectRootOf(t *testing.T) {
+	client := newTestClient(t, "monorepo")
+	repoDir := filepath.Join(client.Configuration().UserConfiguration.RepoRoots[0], "monorepo")
+	for name, content := range map[string]string{"web/package.json": "{}", "web/src/app.js": "", "cmd/api/main.go": ""} {
+		os.MkdirAll(filepath.Dir(filepath.Join(repoDir, name)), 0755)
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.BuildRepoIndex(1, false); err != nil {
+		t.Fatalf("BuildRepoIndex failed: %v", err)
+	}
+
+	for file, expected := range map[string]string{"web/src/app.js": "web", "cmd/api/main.go": "."} {
+		if root, err := client.ProjectRootOf(filepath.Join(repoDir, file)); err != nil || root != filepath.Join(repoDir, expected) {
+			t.Errorf("Expected the project of %s to be %s, got %s (%v)", file, expected, root, err)
+		}
+	}
+
+	// Without its marker file, the repository root is still found through the index
+	os.Remove(filepath.Join(repoDir, "go.mod"))
+	if root, err := client.ProjectRootOf(filepath.Join(repoDir, "cmd/api/main.go")); err != nil || root != repoDir {
+		t.Errorf("Expected the indexed repository to be the project, got %s (%v)", root, err)
+	}
+}
+
+func TestClient_RecordRanksSelectionsFirst(t *testing.T) {
+	client := newTestClient(t, "service-a", "service-b")
+	if err := client.BuildRepoIndex(1, false); err != nil {
+		t.Fatalf("BuildRepoIndex failed: %v", err)
+	}
+
+	repos, _ := client.FindRepos(RepoSearchOptions{NoDirs: true}, []string{"service"})
+	if len(repos) != 2 || repos[0].Item.FullName != "service-a" {
+		t.Fatalf("Expected service-a first without history, got %v", repos)
+	}
+
+	if err := client.Record(repos[1].Item.ToString()); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	repos, _ = client.FindRepos(RepoSearchOptions{NoDirs: true}, []string{"service"})
+	if repos[0].Item.FullName != "service-b" {
+		t.Errorf("Expected the recorded service-b first, got %v", repos)
+	}
+}
+
+func TestClient_MissingIndex(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.FindProjects(SearchOptions{}, nil)
+	var missingIndexError *csvHelper.MissingIndexError
+	if !errors.As(err, &missingIndexError) {
+		t.Errorf("Expected a MissingIndexError, got %v", err)
+	}
+}
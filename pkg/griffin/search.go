@@ -0,0 +1,78 @@
+package griffin
+
+import (
+	"sort"
+	"time"
+
+	history "ronkitay.com/griffin/pkg/history"
+	matcher "ronkitay.com/griffin/pkg/matcher"
+)
+
+// remoteMatchable matches a repository by its remotes in addition to its names
+type remoteMatchable struct {
+	Repo
+}
+
+func (repo remoteMatchable) Matchable() []string {
+	return repo.MatchableWithRemotes()
+}
+
+func wrapRemoteMatchables(repos []Repo) []remoteMatchable {
+	var result []remoteMatchable
+	for _, repo := range repos {
+		result = append(result, remoteMatchable{repo})
+	}
+	return result
+}
+
+func unwrapRemoteMatchables(repos []matcher.Ranked[remoteMatchable]) []matcher.Ranked[Repo] {
+	var result []matcher.Ranked[Repo]
+	for _, repo := range repos {
+		result = append(result, matcher.Ranked[Repo]{Item: repo.Item.Repo, Score: repo.Score})
+	}
+	return result
+}
+
+// matchItems ranks the items matching the filters, or - in regex mode - keeps the matching items in index order.
+func matchItems[T matcher.Matchable](allItems []T, regex bool, args []string) ([]matcher.Ranked[T], error) {
+	if !regex {
+		return matcher.RankItems(allItems, args), nil
+	}
+
+	regexPattern, err := matcher.BuildPattern(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []matcher.Ranked[T]
+	for _, item := range matcher.MatchItems(allItems, regexPattern) {
+		result = append(result, matcher.Ranked[T]{Item: item})
+	}
+	return result, nil
+}
+
+type locatable interface {
+	ToString() string
+}
+
+// usageHistory returns the usage history, which is only read again once it changes.
+func (client *Client) usageHistory() (*history.History, error) {
+	return client.usage.get(client.configuration.HistoryLocation, func() (*history.History, error) {
+		return history.Load(client.configuration)
+	})
+}
+
+// rankByHistory adds the frecency of each item to its score and re-orders the items accordingly
+func rankByHistory[T locatable](usage *history.History, rankedItems []matcher.Ranked[T]) []matcher.Ranked[T] {
+	now := time.Now()
+
+	for i := range rankedItems {
+		rankedItems[i].Score += usage.Bonus(rankedItems[i].Item.ToString(), now)
+	}
+
+	sort.SliceStable(rankedItems, func(i, j int) bool {
+		return rankedItems[i].Score > rankedItems[j].Score
+	})
+
+	return rankedItems
+}
@@ -0,0 +1,156 @@
+package history
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	config "ronkitay.com/griffin/pkg/configuration"
+	csvHelper "ronkitay.com/griffin/pkg/csv"
+)
+
+// Once the ranks of all entries add up to more than this, they are all aged so that
+// old favorites fade out and entries that are no longer used are dropped
+const maxTotalRank = 1000
+
+// Weight of the frecency bonus relative to match scores
+const bonusFactor = 10
+
+// Entry is a path selected from griffin, how often (decayed by aging) and when it was last selected.
+type Entry struct {
+	Path       string
+	Rank       float64
+	LastAccess int64
+}
+
+// INDEX_SCHEMA lists the columns of the history, in the order of AsCsvRecord.
+var INDEX_SCHEMA = csvHelper.Schema{
+	Version:       2,
+	Columns:       []string{"path", "rank", "lastAccess"},
+	LegacyColumns: []string{"path", "rank", "lastAccess"},
+}
+
+func (datum Entry) AsCsvRecord() []string {
+	return []string{datum.Path, strconv.FormatFloat(datum.Rank, 'f', -1, 64), strconv.FormatInt(datum.LastAccess, 10)}
+}
+
+func fromCsvRecord(record csvHelper.IndexRecord) (Entry, error) {
+	if record.Get("path") == "" {
+		return Entry{}, errors.New("invalid history entry")
+	}
+	rank, err := strconv.ParseFloat(record.Get("rank"), 64)
+	if err != nil {
+		return Entry{}, err
+	}
+	lastAccess, err := strconv.ParseInt(record.Get("lastAccess"), 10, 64)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Path: record.Get("path"), Rank: rank, LastAccess: lastAccess}, nil
+}
+
+// History is the usage history used to rank frequently and recently used paths first.
+type History struct {
+	location string
+	entries  map[string]Entry
+}
+
+// Load reads the usage history, which is empty until something is recorded.
+func Load(configuration config.Configuration) (*History, error) {
+	location := configuration.HistoryLocation
+
+	entries, err := loadEntries(location, configuration.Warn)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &History{location: location, entries: map[string]Entry{}}
+	for _, entry := range entries {
+		history.entries[entry.Path] = entry
+	}
+	return history, nil
+}
+
+func loadEntries(location string, warn func(format string, args ...any)) ([]Entry, error) {
+	if _, err := os.Stat(location); err != nil {
+		return nil, nil
+	}
+	return csvHelper.LoadIndex(location, INDEX_SCHEMA, fromCsvRecord, warn)
+}
+
+// Record registers that path was selected at the given time.
+func (history *History) Record(path string, now time.Time) {
+	path = normalize(path)
+
+	entry := history.entries[path]
+	history.entries[path] = Entry{Path: path, Rank: entry.Rank + 1, LastAccess: now.Unix()}
+
+	totalRank := 0.0
+	for _, entry := range history.entries {
+		totalRank += entry.Rank
+	}
+
+	if totalRank > maxTotalRank {
+		history.age(maxTotalRank * 0.9 / totalRank)
+	}
+}
+
+func (history *History) age(factor float64) {
+	for path, entry := range history.entries {
+		entry.Rank *= factor
+		if entry.Rank < 1 {
+			delete(history.entries, path)
+		} else {
+			history.entries[path] = entry
+		}
+	}
+}
+
+func (history *History) Save() error {
+	var entries []Entry
+	for _, entry := range history.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return csvHelper.SaveIndex(history.location, INDEX_SCHEMA, entries)
+}
+
+// Frecency combines how often and how recently path was selected - the more recent,
+// the more each selection counts.
+func (history *History) Frecency(path string, now time.Time) float64 {
+	entry, found := history.entries[normalize(path)]
+	if !found {
+		return 0
+	}
+
+	age := now.Sub(time.Unix(entry.LastAccess, 0))
+	switch {
+	case age < time.Hour:
+		return entry.Rank * 4
+	case age < 24*time.Hour:
+		return entry.Rank * 2
+	case age < 7*24*time.Hour:
+		return entry.Rank / 2
+	default:
+		return entry.Rank / 4
+	}
+}
+
+// Bonus is the frecency of path on the same scale as match scores, growing slowly so that
+// frequently used paths are favored without drowning out how well they match.
+func (history *History) Bonus(path string, now time.Time) int {
+	return int(bonusFactor * math.Log1p(history.Frecency(path, now)))
+}
+
+func normalize(path string) string {
+	if absolutePath, err := filepath.Abs(path); err == nil {
+		return absolutePath
+	}
+	return filepath.Clean(path)
+}
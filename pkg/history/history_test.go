@@ -0,0 +1,63 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFrecency(t *testing.T) {
+	now := time.Now()
+	history := &History{location: filepath.Join(t.TempDir(), "history.list"), entries: map[string]Entry{}}
+
+	for i := 0; i < 5; i++ {
+		history.Record("/src/often-long-ago", now.Add(-30*24*time.Hour))
+	}
+	history.Record("/src/once-just-now", now)
+	history.Record("/src/thrice-today/", now.Add(-4*time.Hour))
+	history.Record("/src/thrice-today", now.Add(-3*time.Hour))
+	history.Record("/src/thrice-today", now.Add(-2*time.Hour))
+
+	if frecency := history.Frecency("/src/never", now); frecency != 0 {
+		t.Errorf("Expected no frecency for an unused path, got %f", frecency)
+	}
+
+	oftenLongAgo := history.Frecency("/src/often-long-ago", now)
+	onceJustNow := history.Frecency("/src/once-just-now", now)
+	thriceToday := history.Frecency("/src/thrice-today", now)
+	if !(thriceToday > onceJustNow && onceJustNow > oftenLongAgo) {
+		t.Errorf("Unexpected frecency order: thrice today %f, once just now %f, often long ago %f", thriceToday, onceJustNow, oftenLongAgo)
+	}
+
+	if err := history.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	entries, err := loadEntries(history.location, t.Logf)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	loaded := &History{location: history.location, entries: map[string]Entry{}}
+	for _, entry := range entries {
+		loaded.entries[entry.Path] = entry
+	}
+	if loaded.Frecency("/src/thrice-today", now) != thriceToday {
+		t.Errorf("Expected frecency to survive a save and load")
+	}
+}
+
+func TestAging(t *testing.T) {
+	now := time.Now()
+	history := &History{entries: map[string]Entry{}}
+
+	history.Record("/src/old", now)
+	for i := 0; i < maxTotalRank; i++ {
+		history.Record("/src/favorite", now)
+	}
+
+	if _, found := history.entries["/src/old"]; found {
+		t.Errorf("Expected rarely used entries to be dropped once ranks are aged")
+	}
+	if rank := history.entries["/src/favorite"].Rank; rank > maxTotalRank {
+		t.Errorf("Expected ranks to be aged below %d, got %f", maxTotalRank, rank)
+	}
+}
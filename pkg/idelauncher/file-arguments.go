@@ -0,0 +1,100 @@
+package idelauncher
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// fileConvention is how a family of IDEs is told to open a file of a project at a line
+type fileConvention struct {
+	// names are the prefixes of the normalized names of the IDEs, e.g. goland for "GoLand.app"
+	names []string
+	// arguments open target.File at line, which is empty when no line is given
+	arguments func(target Target, line string) []string
+}
+
+// FILE_CONVENTIONS are the conventions of known IDEs - others are given the project and the file, without the line.
+// Terminal editors are left out: IDEs are started in the background, without a terminal to run them in.
+var FILE_CONVENTIONS = []fileConvention{
+	{
+		names: []string{"idea", "intellij", "goland", "pycharm", "webstorm", "phpstorm", "clion", "rider", "rustrover", "rubymine", "datagrip", "androidstudio"},
+		arguments: func(target Target, line string) []string {
+			if line == "" {
+				return []string{target.Path, target.File}
+			}
+			return []string{target.Path, "--line", line, target.File}
+		},
+	},
+	{
+		names: []string{"code", "visualstudiocode", "codium", "vscodium", "cursor", "windsurf"},
+		arguments: func(target Target, line string) []string {
+			if line == "" {
+				return []string{target.Path, target.File}
+			}
+			return []string{target.Path, "--goto", target.File + ":" + line}
+		},
+	},
+	{
+		names: []string{"zed", "subl", "sublimetext"},
+		arguments: func(target Target, line string) []string {
+			if line == "" {
+				return []string{target.Path, target.File}
+			}
+			return []string{target.Path, target.File + ":" + line}
+		},
+	},
+}
+
+// targetArguments are the arguments that open target in ide: the project directory, followed by the file and line
+// in the convention of ide, when target has a file.
+func targetArguments(ide string, target Target) []string {
+	if target.File == "" {
+		return []string{target.Path}
+	}
+
+	line := ""
+	if target.Line > 0 {
+		line = strconv.Itoa(target.Line)
+	}
+	if convention, found := conventionOf(ide); found {
+		return convention.arguments(target, line)
+	}
+	return []string{target.Path, target.File}
+}
+
+// conventionOf finds the convention of ide by its name - or by the name of its command, when it has arguments
+func conventionOf(ide string) (fileConvention, bool) {
+	candidates := []string{ide}
+	if words, err := splitCommand(ide); err == nil && len(words) > 1 {
+		candidates = append(candidates, words[0])
+	}
+
+	for _, candidate := range candidates {
+		name := ideName(candidate)
+		for _, convention := range FILE_CONVENTIONS {
+			for _, prefix := range convention.names {
+				if strings.HasPrefix(name, prefix) {
+					return convention, true
+				}
+			}
+		}
+	}
+	return fileConvention{}, false
+}
+
+// ideName normalizes the base name of an IDE, without .app
+func ideName(ide string) string {
+	return normalizeName(strings.TrimSuffix(filepath.Base(ide), ".app"))
+}
+
+// normalizeName keeps the lowercase letters and digits of name, so "Visual Studio Code" matches visual-studio-code
+func normalizeName(name string) string {
+	return strings.Map(func(char rune) rune {
+		if unicode.IsLetter(char) || unicode.IsDigit(char) {
+			return unicode.ToLower(char)
+		}
+		return -1
+	}, name)
+}
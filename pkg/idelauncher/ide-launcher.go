@@ -1,154 +1,300 @@
 package idelauncher
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
-	
+	"regexp"
+	"strconv"
+	"strings"
+
 	config "ronkitay.com/griffin/pkg/configuration"
 )
 
-const (
-	UNSUPPORTED_LANGUAGE = "Not Supported"
+// ErrMissingDefaultIDE is returned when no default IDE is configured.
+var ErrMissingDefaultIDE = errors.New("missing DefaultIDE configuration")
 
-	PYTHON_LANGUAGE  = "python"
-	JAVA_LANGUAGE    = "java"
-	KOTLIN_LANGUAGE  = "kotlin"
-	NODE_JS_LANGUAGE = "node"
-	GO_LANGUAGE = "golang"
-	RUST_LANGUAGE    = "rust"
-)
+// PLACEHOLDER matches the placeholders of IDE command templates, e.g. {path}
+var PLACEHOLDER = regexp.MustCompile(`\{\w+\}`)
 
-func OpenInIDE(projectDir string) {
-	ideConfiguration := config.LoadConfiguration().UserConfiguration.IdeConfiguration
+// PLACEHOLDERS are the placeholders IDE command templates may have
+var PLACEHOLDERS = []string{"path", "url", "language", "file", "line"}
 
-	if ideConfiguration.DefaultIDE == "" {
-		fmt.Println("Missing DefaultIDE configuration")
-		os.Exit(12)
-	}
+// Target is what an IDE is opened on
+type Target struct {
+	// Path is the project directory
+	Path string
+	// RepoURL is the primary remote URL of the repository containing Path, when known
+	RepoURL string
+	// Languages of the project, the primary one first - detected when not set
+	Languages []string
+	// File and Line are a file of the project, and a line in it, to open
+	File string
+	Line int
+}
 
-	language := detectLanguage(projectDir)
-	ide := ideOrDefault(language, ideConfiguration)
-	openIDE(ide, projectDir)
+// OpenInIDE opens target in the IDE configured for its primary language - or for its other languages, when the
+// primary one has none. The languages are detected with configuration.LanguageDetector.
+func OpenInIDE(configuration config.Configuration, target Target) error {
+	return open(configuration, "", target, false)
 }
 
-func OpenInAlternativeIDE(projectDir string) {
-	ideConfiguration := config.LoadConfiguration().UserConfiguration.IdeConfiguration
+func OpenInAlternativeIDE(configuration config.Configuration, target Target) error {
+	return open(configuration, "", target, true)
+}
 
-	if ideConfiguration.DefaultIDE == "" {
-		fmt.Println("Missing DefaultIDE configuration")
-		os.Exit(12)
+// IDEs returns the IDEs configured for the languages of projectDir, starting with the one OpenInIDE uses.
+func IDEs(configuration config.Configuration, projectDir string) ([]string, error) {
+	languages, err := detectLanguages(configuration, projectDir)
+	if err != nil {
+		return nil, err
 	}
+	return configuration.UserConfiguration.IdeConfiguration.IDEs(languages...), nil
+}
 
-	language := detectLanguage(projectDir)
-	ide := alternativeIdeOrDefault(language, ideConfiguration)
-	openIDE(ide, projectDir)
+// OpenWith opens target in ide. It fails with ErrMissingDefaultIDE when ide is empty, which it is when neither
+// the language of a project nor the default IDE are configured.
+func OpenWith(configuration config.Configuration, ide string, target Target) error {
+	if ide == "" {
+		return ErrMissingDefaultIDE
+	}
+	return open(configuration, ide, target, false)
 }
 
-func detectLanguage(projectDir string) string {
-	if exists(filepath.Join(projectDir, "requirements.txt")) || exists(filepath.Join(projectDir, "Pipfile"))  || exists(filepath.Join(projectDir, "poetry.toml")) || exists(filepath.Join(projectDir, "pyproject.toml")) {
-		return PYTHON_LANGUAGE
-	} else if exists(filepath.Join(projectDir, "build.gradle.kts")) || exists(filepath.Join(projectDir, "settings.gradle.kts")) {
-		return KOTLIN_LANGUAGE
-	} else if exists(filepath.Join(projectDir, "go.mod")) {
-		return GO_LANGUAGE
-	} else if exists(filepath.Join(projectDir, "build.gradle")) || exists(filepath.Join(projectDir, "pom.xml")) || exists(filepath.Join(projectDir, "settings.gradle")) {
-		return JAVA_LANGUAGE
-	} else if exists(filepath.Join(projectDir, "package.json")) {
-		return NODE_JS_LANGUAGE
-	} else if exists(filepath.Join(projectDir, "Cargo.toml")) {
-		return RUST_LANGUAGE
-	} else {
-		return UNSUPPORTED_LANGUAGE
+func open(configuration config.Configuration, ide string, target Target, alternative bool) error {
+	launch, err := ResolveIDE(configuration, ide, target, alternative)
+	if err != nil {
+		return err
 	}
+	return launch.start()
 }
 
-func ideOrDefault(language string, ideConfiguration config.IdeConfiguration) string {
-	switch language {
-	case PYTHON_LANGUAGE:
-		return ifNull(ideConfiguration.Python, ideConfiguration.DefaultIDE)
-	case GO_LANGUAGE:
-		return ifNull(ideConfiguration.GoLang, ideConfiguration.DefaultIDE)
-	case JAVA_LANGUAGE:
-		return ifNull(ideConfiguration.Java, ideConfiguration.DefaultIDE)
-	case KOTLIN_LANGUAGE:
-		return ifNull(ideConfiguration.Kotlin, ideConfiguration.DefaultIDE)
-	case NODE_JS_LANGUAGE:
-		return ifNull(ideConfiguration.NodeJS, ideConfiguration.DefaultIDE)
-	case RUST_LANGUAGE:
-		return ifNull(ideConfiguration.Rust, ideConfiguration.DefaultIDE)
-	default:
-		return ideConfiguration.DefaultIDE
+// detectLanguages returns the languages of projectDir, the primary one first
+func detectLanguages(configuration config.Configuration, projectDir string) ([]string, error) {
+	detector, err := configuration.LanguageDetector()
+	if err != nil {
+		return nil, err
 	}
+	return detector.DetectAll(projectDir), nil
 }
 
-func fallbackToDefaultAlternativeIDE(ide string, ideConfiguration config.IdeConfiguration) string {
-	return ifNull(ide, ifNull(ideConfiguration.DefaultIDEAlternative, ideConfiguration.DefaultIDE))
+// Launch is the command that opens a project in an IDE
+type Launch struct {
+	// IDE is the IDE as configured
+	IDE string
+	// Command is the executable, resolved to its full path, followed by its arguments
+	Command []string
+	// ResolvedFrom tells how the executable was found, such as "PATH" or the desktop file it comes from
+	ResolvedFrom string
 }
 
-func alternativeIdeOrDefault(language string, ideConfiguration config.IdeConfiguration) string {
-	switch language {
-	case PYTHON_LANGUAGE:
-		return fallbackToDefaultAlternativeIDE(ideConfiguration.PythonAlternative, ideConfiguration)
-	case GO_LANGUAGE:
-		return fallbackToDefaultAlternativeIDE(ideConfiguration.GoLangAlternative, ideConfiguration)
-	case JAVA_LANGUAGE:
-		return fallbackToDefaultAlternativeIDE(ideConfiguration.JavaAlternative, ideConfiguration)
-	case KOTLIN_LANGUAGE:
-		return fallbackToDefaultAlternativeIDE(ideConfiguration.KotlinAlternative, ideConfiguration)
-	case NODE_JS_LANGUAGE:
-		return fallbackToDefaultAlternativeIDE(ideConfiguration.NodeJSAlternative, ideConfiguration)
-	case RUST_LANGUAGE:
-		return fallbackToDefaultAlternativeIDE(ideConfiguration.RustAlternative, ideConfiguration)
-	default:
-		return fallbackToDefaultAlternativeIDE("", ideConfiguration)
+func (launch Launch) String() string {
+	var words []string
+	for _, word := range launch.Command {
+		if strings.ContainsAny(word, " \t'\"") {
+			word = strconv.Quote(word)
+		}
+		words = append(words, word)
 	}
+	return fmt.Sprintf("%s: %s (resolved from %s)", launch.IDE, strings.Join(words, " "), launch.ResolvedFrom)
 }
 
-func ifNull(nullable string, defaultValue string) string {
-	if nullable == "" {
-		return defaultValue
-	} else {
-		return nullable
+func (launch Launch) start() error {
+	cmd := exec.Command(launch.Command[0], launch.Command[1:]...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error opening IDE (%s): %w", launch, err)
 	}
+	return nil
 }
 
-func exists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+// UnresolvedIDEError is returned when a configured IDE cannot be found
+type UnresolvedIDEError struct {
+	IDE string
+	// Searched are the places the IDE was looked for
+	Searched []string
 }
 
-func openIDE(ide string, projectDir string) {
-	var rootDirectory = projectDir
-	if rootDirectory == "." {
+func (err *UnresolvedIDEError) Error() string {
+	return fmt.Sprintf("cannot find IDE '%s' - searched %s", err.IDE, strings.Join(err.Searched, "; "))
+}
+
+// ResolveIDE returns how ide opens target, on this OS, without opening it. When ide is empty, the IDE (or
+// alternative IDE) configured for the languages of target is used.
+func ResolveIDE(configuration config.Configuration, ide string, target Target, alternative bool) (Launch, error) {
+	if target.Path == "." {
 		currentDir, err := os.Getwd()
 		if err != nil {
-			fmt.Println("Cannot resolve working directory:", err)
-			os.Exit(1)
+			return Launch{}, fmt.Errorf("cannot resolve working directory: %w", err)
+		}
+		target.Path = currentDir
+	}
+
+	if target.Languages == nil {
+		languages, err := detectLanguages(configuration, target.Path)
+		if err != nil {
+			return Launch{}, err
 		}
-		rootDirectory = currentDir
+		target.Languages = languages
 	}
 
-	var cmd *exec.Cmd
-	switch detectOS() {
-	case "darwin":
-		cmd = exec.Command("open", "-na", ide, "--args", rootDirectory)
-	case "linux":
-		cmd = exec.Command(ide, rootDirectory)
-	default:
-		fmt.Println("Unsupported OS for launching IDE")
-		return
+	if ide == "" && alternative {
+		ide = configuration.UserConfiguration.IdeConfiguration.AlternativeIDE(target.Languages...)
+	} else if ide == "" {
+		ide = configuration.UserConfiguration.IdeConfiguration.IDE(target.Languages...)
+	}
+	if ide == "" {
+		return Launch{}, ErrMissingDefaultIDE
 	}
 
-	err := cmd.Start()
+	if IsTemplate(ide) {
+		return resolveTemplate(ide, target)
+	}
+	return resolveLaunch(ide, targetArguments(ide, target))
+}
+
+// IsTemplate tells whether ide is a command template, such as `code --new-window {path}`, rather than the name of an IDE
+func IsTemplate(ide string) bool {
+	return PLACEHOLDER.MatchString(ide)
+}
+
+// resolveTemplate expands the placeholders of the command template ide. Words between [ and ], such as
+// `[--goto {file}:{line}]`, are left out together when one of their placeholders has no value. Any other placeholder
+// without a value is an error.
+func resolveTemplate(ide string, target Target) (Launch, error) {
+	words, err := splitCommand(ide)
 	if err != nil {
-		fmt.Println("Error opening IDE (", ide, "):", err)
+		return Launch{}, err
+	}
+
+	values := target.placeholderValues()
+	var command, group []string
+	inGroup, groupComplete := false, true
+	for _, word := range words {
+		opensGroup := !inGroup && strings.HasPrefix(word, "[")
+		if opensGroup {
+			word, inGroup, groupComplete, group = word[1:], true, true, nil
+		}
+		closesGroup := inGroup && strings.HasSuffix(word, "]")
+		if closesGroup {
+			word = word[:len(word)-1]
+		}
+
+		expanded, missing, err := expandPlaceholders(ide, word, values)
+		if err != nil {
+			return Launch{}, err
+		}
+		switch {
+		case word == "" && (opensGroup || closesGroup):
+			// A bracket written as a word of its own, as in [ --goto {file}:{line} ]
+		case inGroup:
+			group = append(group, expanded)
+			groupComplete = groupComplete && missing == ""
+		case missing != "":
+			return Launch{}, fmt.Errorf("IDE command '%s' needs %s, which has no value when opening %s - put it between [ and ] to leave it out", ide, missing, target.Path)
+		default:
+			command = append(command, expanded)
+		}
+
+		if closesGroup {
+			if groupComplete {
+				command = append(command, group...)
+			}
+			inGroup = false
+		}
+	}
+	if inGroup {
+		return Launch{}, fmt.Errorf("unterminated [ in IDE command '%s'", ide)
+	}
+	if len(command) == 0 {
+		return Launch{}, fmt.Errorf("IDE command '%s' has no executable", ide)
+	}
+
+	var searched []string
+	executable, resolvedFrom, found := findExecutable(command[0], &searched)
+	if !found {
+		return Launch{}, &UnresolvedIDEError{IDE: ide, Searched: searched}
+	}
+	command[0] = executable
+	return Launch{IDE: ide, Command: command, ResolvedFrom: resolvedFrom}, nil
+}
+
+// expandPlaceholders replaces the placeholders of word by their values, and returns the first placeholder that has none
+func expandPlaceholders(ide string, word string, values map[string]string) (string, string, error) {
+	var unknown, missing string
+	expanded := PLACEHOLDER.ReplaceAllStringFunc(word, func(placeholder string) string {
+		value, known := values[placeholder[1:len(placeholder)-1]]
+		if !known && unknown == "" {
+			unknown = placeholder
+		}
+		if value == "" && missing == "" {
+			missing = placeholder
+		}
+		return value
+	})
+	if unknown != "" {
+		return "", "", fmt.Errorf("unknown placeholder %s in IDE command '%s' (supported placeholders: {%s})", unknown, ide, strings.Join(PLACEHOLDERS, "}, {"))
 	}
+	return expanded, missing, nil
 }
 
-func detectOS() string {
-	return runtime.GOOS
+// placeholderValues maps the PLACEHOLDERS to their values for target, empty when it has none
+func (target Target) placeholderValues() map[string]string {
+	values := map[string]string{"path": target.Path, "url": target.RepoURL, "language": "", "file": target.File, "line": ""}
+	if len(target.Languages) > 0 {
+		values["language"] = target.Languages[0]
+	}
+	if target.Line > 0 {
+		values["line"] = strconv.Itoa(target.Line)
+	}
+	return values
+}
+
+// splitCommand splits command into words like a shell does, keeping quoted words together
+func splitCommand(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	for _, char := range command {
+		switch {
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(char)
+		case char == '\'' || char == '"':
+			quote, inWord = char, true
+		case char == ' ' || char == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(char)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", command)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
 }
 
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(os.Getenv("HOME"), path[2:])
+	}
+	return path
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0111 != 0
+}
@@ -0,0 +1,106 @@
+package idelauncher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	for _, test := range []struct {
+		command  string
+		expected []string
+	}{
+		{"code", []string{"code"}},
+		{"code  --new-window\t--profile work", []string{"code", "--new-window", "--profile", "work"}},
+		{`"/opt/My IDE/bin/ide" --title 'a "b"' ""`, []string{"/opt/My IDE/bin/ide", "--title", `a "b"`, ""}},
+	} {
+		if words, err := splitCommand(test.command); err != nil || !reflect.DeepEqual(words, test.expected) {
+			t.Errorf("Expected %q to split into %q, got %q (%v)", test.command, test.expected, words, err)
+		}
+	}
+
+	if _, err := splitCommand(`code "unterminated`); err == nil {
+		t.Errorf("Expected an unterminated quote to be rejected")
+	}
+}
+
+func TestResolveTemplate(t *testing.T) {
+	binDir := t.TempDir()
+	code := filepath.Join(binDir, "code")
+	if err := os.WriteFile(code, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+
+	target := Target{Path: "/repo/api", RepoURL: "https://github.com/org/repo", Languages: []string{"go", "node"}}
+	withFile := target
+	withFile.File, withFile.Line = "/repo/api/main.go", 42
+
+	for _, test := range []struct {
+		template string
+		target   Target
+		expected []string
+	}{
+		{"code --new-window --profile work {path}", target, []string{code, "--new-window", "--profile", "work", "/repo/api"}},
+		{"code --folder-uri=vscode-remote://{language}+{url} [--goto {file}:{line}]", target,
+			[]string{code, "--folder-uri=vscode-remote://go+https://github.com/org/repo"}},
+		{"code {path} [--goto {file}:{line}]", withFile, []string{code, "/repo/api", "--goto", "/repo/api/main.go:42"}},
+		{"code {path} [ --goto {file}:{line} ]", withFile, []string{code, "/repo/api", "--goto", "/repo/api/main.go:42"}},
+		{"code {path} [ --goto {file}:{line} ]", target, []string{code, "/repo/api"}},
+		{"code {path} [--line={line}] [{file}]", Target{Path: "/repo/api", File: "/repo/api/main.go"}, []string{code, "/repo/api", "/repo/api/main.go"}},
+		{code + " '{path}'", target, []string{code, "/repo/api"}},
+	} {
+		if !IsTemplate(test.template) {
+			t.Errorf("Expected %q to be a template", test.template)
+		}
+		launch, err := resolveTemplate(test.template, test.target)
+		if err != nil {
+			t.Errorf("Expected %q to resolve, got %v", test.template, err)
+		} else if !reflect.DeepEqual(launch.Command, test.expected) {
+			t.Errorf("Expected %q to resolve to %q, got %q", test.template, test.expected, launch.Command)
+		}
+	}
+
+	if _, err := resolveTemplate("code {folder}", target); err == nil || !strings.Contains(err.Error(), "{folder}") {
+		t.Errorf("Expected an unknown placeholder to be rejected, got %v", err)
+	}
+	if _, err := resolveTemplate("code {path} --goto {file}:{line}", target); err == nil || !strings.Contains(err.Error(), "{file}") {
+		t.Errorf("Expected a placeholder without a value outside [ ] to be rejected, got %v", err)
+	}
+	if _, err := resolveTemplate("code {path} [--goto {file}", withFile); err == nil {
+		t.Errorf("Expected an unterminated [ to be rejected")
+	}
+	if _, err := resolveTemplate("idea {path}", target); err == nil {
+		t.Errorf("Expected a missing executable to be rejected")
+	}
+	if IsTemplate("Visual Studio Code") {
+		t.Errorf("Expected a name not to be a template")
+	}
+}
+
+func TestTargetArguments(t *testing.T) {
+	project := Target{Path: "/repo"}
+	file := Target{Path: "/repo", File: "/repo/main.go"}
+	fileAndLine := Target{Path: "/repo", File: "/repo/main.go", Line: 42}
+
+	for _, test := range []struct {
+		ide      string
+		target   Target
+		expected []string
+	}{
+		{"GoLand.app", project, []string{"/repo"}},
+		{"GoLand.app", fileAndLine, []string{"/repo", "--line", "42", "/repo/main.go"}},
+		{"IntelliJ IDEA CE.app", file, []string{"/repo", "/repo/main.go"}},
+		{"Visual Studio Code", fileAndLine, []string{"/repo", "--goto", "/repo/main.go:42"}},
+		{"/usr/bin/codium --wait", fileAndLine, []string{"/repo", "--goto", "/repo/main.go:42"}},
+		{"zed", fileAndLine, []string{"/repo", "/repo/main.go:42"}},
+		{"kate", fileAndLine, []string{"/repo", "/repo/main.go"}},
+	} {
+		if arguments := targetArguments(test.ide, test.target); !reflect.DeepEqual(arguments, test.expected) {
+			t.Errorf("Expected %q for %s with %+v, got %q", test.expected, test.ide, test.target, arguments)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package idelauncher
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// TOOLBOX_SCRIPTS_DIR is where JetBrains Toolbox puts the launch scripts of the IDEs it installs, under $HOME
+const TOOLBOX_SCRIPTS_DIR = ".local/share/JetBrains/Toolbox/scripts"
+
+// FILE_FIELD_CODES are replaced by the opened paths in the Exec line of desktop files - other field codes are dropped
+var FILE_FIELD_CODES = []string{"%f", "%F", "%u", "%U"}
+
+// desktopEntry is an application of an XDG desktop file
+type desktopEntry struct {
+	path string
+	// id is the name of the desktop file without .desktop, e.g. com.visualstudio.code
+	id   string
+	name string
+	exec string
+}
+
+// resolveLaunch finds ide as a path, in the PATH (and the JetBrains Toolbox scripts), or by the name or id of a desktop
+// file - which also finds flatpak and snap applications. Names from macOS configurations such as "GoLand.app" are found
+// as goland. An IDE that cannot be found as a whole is taken as a command followed by its arguments.
+func resolveLaunch(ide string, targetArgs []string) (Launch, error) {
+	var searched []string
+	if launch, found := resolveName(ide, ide, nil, targetArgs, &searched); found {
+		return launch, nil
+	}
+
+	words, err := splitCommand(ide)
+	if err != nil {
+		return Launch{}, err
+	}
+	if len(words) > 0 && !(len(words) == 1 && words[0] == ide) {
+		if launch, found := resolveName(ide, words[0], words[1:], targetArgs, &searched); found {
+			return launch, nil
+		}
+	}
+
+	return Launch{}, &UnresolvedIDEError{IDE: ide, Searched: searched}
+}
+
+// resolveName resolves the executable called name, which is run with args followed by targetArgs
+func resolveName(ide string, name string, args []string, targetArgs []string, searched *[]string) (Launch, bool) {
+	launch := func(executable string, resolvedFrom string) Launch {
+		return Launch{IDE: ide, Command: append(append([]string{executable}, args...), targetArgs...), ResolvedFrom: resolvedFrom}
+	}
+
+	if executable, resolvedFrom, found := findExecutable(name, searched); found {
+		return launch(executable, resolvedFrom), true
+	}
+	if strings.Contains(name, "/") {
+		return Launch{}, false
+	}
+
+	applicationDirs := applicationDirs()
+	*searched = append(*searched, "desktop files in "+strings.Join(applicationDirs, ", "))
+	for _, applicationDir := range applicationDirs {
+		if entry, found := findDesktopEntry(applicationDir, name); found {
+			if command, ok := entry.command(args, targetArgs); ok {
+				return Launch{IDE: ide, Command: command, ResolvedFrom: entry.path}, true
+			}
+		}
+	}
+
+	return Launch{}, false
+}
+
+// findExecutable finds name as a path, when it has a /, or in the PATH and the JetBrains Toolbox scripts under any of
+// its executableNames. It returns the executable and where it was found.
+func findExecutable(name string, searched *[]string) (string, string, bool) {
+	if strings.Contains(name, "/") {
+		path := expandHome(name)
+		*searched = append(*searched, path)
+		return path, "its path", isExecutable(path)
+	}
+
+	candidates := executableNames(name)
+	toolboxDir := filepath.Join(os.Getenv("HOME"), TOOLBOX_SCRIPTS_DIR)
+	*searched = append(*searched, "PATH and "+toolboxDir+" for "+strings.Join(candidates, ", "))
+	for _, candidate := range candidates {
+		if executable, err := exec.LookPath(candidate); err == nil {
+			return executable, "PATH", true
+		}
+		if executable := filepath.Join(toolboxDir, candidate); isExecutable(executable) {
+			return executable, "JetBrains Toolbox", true
+		}
+	}
+	return "", "", false
+}
+
+// executableNames are the names an executable called name may have, e.g. goland for "GoLand.app"
+func executableNames(name string) []string {
+	baseName := strings.TrimSuffix(name, ".app")
+	lowerName := strings.ToLower(baseName)
+
+	var names []string
+	for _, candidate := range []string{name, baseName, lowerName, strings.ReplaceAll(lowerName, " ", "-"), strings.ReplaceAll(lowerName, " ", "")} {
+		if !slices.Contains(names, candidate) {
+			names = append(names, candidate)
+		}
+	}
+	return names
+}
+
+// applicationDirs are the directories holding desktop files, in the order of precedence of the XDG specification,
+// followed by the directories of flatpak and snap applications
+func applicationDirs() []string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(os.Getenv("HOME"), ".local/share")
+	}
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+
+	var dirs []string
+	for _, dataDir := range append(append([]string{dataHome}, filepath.SplitList(dataDirs)...),
+		filepath.Join(dataHome, "flatpak/exports/share"), "/var/lib/flatpak/exports/share", "/var/lib/snapd/desktop") {
+		if dir := filepath.Join(dataDir, "applications"); dataDir != "" && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// findDesktopEntry finds the application of applicationDir whose name or id is name
+func findDesktopEntry(applicationDir string, name string) (desktopEntry, bool) {
+	wanted := normalizeName(strings.TrimSuffix(name, ".app"))
+
+	var found desktopEntry
+	filepath.WalkDir(applicationDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".desktop") {
+			return nil
+		}
+
+		desktopEntry, ok := readDesktopEntry(path)
+		if !ok {
+			return nil
+		}
+		id := strings.TrimSuffix(entry.Name(), ".desktop")
+		shortId := id[strings.LastIndex(id, ".")+1:]
+		if wanted == normalizeName(desktopEntry.name) || wanted == normalizeName(id) || wanted == normalizeName(shortId) {
+			desktopEntry.id = id
+			found = desktopEntry
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found, found.path != ""
+}
+
+// readDesktopEntry reads the [Desktop Entry] of an application's desktop file, unless it is hidden
+func readDesktopEntry(path string) (desktopEntry, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return desktopEntry{}, false
+	}
+	defer file.Close()
+
+	entry := desktopEntry{path: path}
+	inMainSection, isApplication, hidden := false, false, false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inMainSection = line == "[Desktop Entry]"
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !inMainSection || !found {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Name":
+			entry.name = strings.TrimSpace(value)
+		case "Exec":
+			entry.exec = strings.TrimSpace(value)
+		case "Type":
+			isApplication = strings.TrimSpace(value) == "Application"
+		case "Hidden":
+			hidden = strings.TrimSpace(value) == "true"
+		}
+	}
+	return entry, isApplication && !hidden && entry.exec != ""
+}
+
+// command is the Exec line of the entry, with args and targetArgs in place of its file field code (or at its end)
+func (entry desktopEntry) command(args []string, targetArgs []string) ([]string, bool) {
+	words, err := splitCommand(entry.exec)
+	if err != nil || len(words) == 0 {
+		return nil, false
+	}
+
+	executable, err := exec.LookPath(words[0])
+	if err != nil {
+		return nil, false
+	}
+
+	command := []string{executable}
+	placed := false
+	for _, word := range words[1:] {
+		switch {
+		case slices.Contains(FILE_FIELD_CODES, word) && !placed:
+			command = append(append(command, args...), targetArgs...)
+			placed = true
+		case len(word) == 2 && word[0] == '%' && word != "%%":
+			// Other field codes (icon, name, ...) are not needed to open a directory
+		default:
+			command = append(command, strings.ReplaceAll(word, "%%", "%"))
+		}
+	}
+	if !placed {
+		command = append(append(command, args...), targetArgs...)
+	}
+	return command, true
+}
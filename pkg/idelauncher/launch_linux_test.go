@@ -0,0 +1,77 @@
+package idelauncher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// linuxEnvironment points PATH, HOME and the XDG data directories at temporary directories, and returns the bin
+// and applications directories
+func linuxEnvironment(t *testing.T) (string, string) {
+	home := t.TempDir()
+	binDir := filepath.Join(home, "bin")
+	applicationsDir := filepath.Join(home, "data", "applications")
+	for _, dir := range []string{binDir, applicationsDir, filepath.Join(home, TOOLBOX_SCRIPTS_DIR)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("HOME", home)
+	t.Setenv("PATH", binDir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("XDG_DATA_DIRS", filepath.Join(home, "none"))
+	return binDir, applicationsDir
+}
+
+func writeFile(t *testing.T, path string, content string, mode os.FileMode) {
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveLaunch_Linux(t *testing.T) {
+	binDir, applicationsDir := linuxEnvironment(t)
+	toolboxDir := filepath.Join(os.Getenv("HOME"), TOOLBOX_SCRIPTS_DIR)
+	writeFile(t, filepath.Join(binDir, "zed"), "", 0755)
+	writeFile(t, filepath.Join(binDir, "flatpak"), "", 0755)
+	writeFile(t, filepath.Join(toolboxDir, "goland"), "", 0755)
+	writeFile(t, filepath.Join(applicationsDir, "com.visualstudio.code.desktop"),
+		"[Desktop Entry]\nType=Application\nName=Visual Studio Code\nExec=flatpak run com.visualstudio.code --new-window %F\n"+
+			"[Desktop Action new-empty-window]\nExec=flatpak run other %F\n", 0644)
+	writeFile(t, filepath.Join(applicationsDir, "hidden.desktop"), "[Desktop Entry]\nType=Application\nName=Hidden\nExec=zed\nHidden=true\n", 0644)
+
+	for _, test := range []struct {
+		ide          string
+		command      []string
+		resolvedFrom string
+	}{
+		{"zed", []string{filepath.Join(binDir, "zed"), "/project"}, "PATH"},
+		{"zed --wait", []string{filepath.Join(binDir, "zed"), "--wait", "/project"}, "PATH"},
+		{"GoLand.app", []string{filepath.Join(toolboxDir, "goland"), "/project"}, "JetBrains Toolbox"},
+		{"Visual Studio Code", []string{filepath.Join(binDir, "flatpak"), "run", "com.visualstudio.code", "--new-window", "/project"},
+			filepath.Join(applicationsDir, "com.visualstudio.code.desktop")},
+		{"code --reuse-window", []string{filepath.Join(binDir, "flatpak"), "run", "com.visualstudio.code", "--new-window", "--reuse-window", "/project"},
+			filepath.Join(applicationsDir, "com.visualstudio.code.desktop")},
+		{filepath.Join(binDir, "zed"), []string{filepath.Join(binDir, "zed"), "/project"}, "its path"},
+	} {
+		launch, err := resolveLaunch(test.ide, []string{"/project"})
+		if err != nil {
+			t.Errorf("Expected %q to resolve, got %v", test.ide, err)
+			continue
+		}
+		if !reflect.DeepEqual(launch.Command, test.command) || launch.ResolvedFrom != test.resolvedFrom {
+			t.Errorf("Expected %q to resolve to %v from %s, got %v from %s", test.ide, test.command, test.resolvedFrom, launch.Command, launch.ResolvedFrom)
+		}
+	}
+
+	var unresolvedIDEError *UnresolvedIDEError
+	for _, ide := range []string{"Hidden", "idea", "/opt/idea/bin/idea.sh"} {
+		if _, err := resolveLaunch(ide, []string{"/project"}); !errors.As(err, &unresolvedIDEError) {
+			t.Errorf("Expected %q not to resolve, got %v", ide, err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !linux
+
+package idelauncher
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// resolveLaunch opens macOS applications with `open`, which finds them by name
+func resolveLaunch(ide string, targetArgs []string) (Launch, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return Launch{IDE: ide, Command: append([]string{"open", "-na", ide, "--args"}, targetArgs...), ResolvedFrom: "open -na"}, nil
+	default:
+		return Launch{}, fmt.Errorf("unsupported OS for launching IDE: %s", runtime.GOOS)
+	}
+}
+
+// findExecutable finds name as a path, when it has a /, or in the PATH. It returns the executable and where it was found.
+func findExecutable(name string, searched *[]string) (string, string, bool) {
+	if strings.Contains(name, "/") {
+		path := expandHome(name)
+		*searched = append(*searched, path)
+		return path, "its path", isExecutable(path)
+	}
+
+	*searched = append(*searched, "PATH for "+name)
+	if executable, err := exec.LookPath(name); err == nil {
+		return executable, "PATH", true
+	}
+	return "", "", false
+}
@@ -0,0 +1,277 @@
+package language
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The languages detected by DEFAULT_RULES
+const (
+	KOTLIN    = "kotlin"
+	JAVA      = "java"
+	SCALA     = "scala"
+	GO        = "go"
+	RUST      = "rust"
+	CSHARP    = "csharp"
+	CPP       = "cpp"
+	ELIXIR    = "elixir"
+	RUBY      = "ruby"
+	PYTHON    = "python"
+	NODE      = "node"
+	TERRAFORM = "terraform"
+	// ANY is a project of no particular language, such as a directory with just a Makefile
+	ANY = "any"
+)
+
+const (
+	// MAX_CONTENT_SIZE is how much of a file content checks read
+	MAX_CONTENT_SIZE = 64 * 1024
+	// MAX_COUNTED_FILES bounds the files counted to choose the primary language of huge projects
+	MAX_COUNTED_FILES = 20000
+)
+
+// SKIPPED_DIRS hold dependencies and build outputs rather than the sources of projects
+var SKIPPED_DIRS = []string{".git", ".terraform", "node_modules", ".venv", "venv", "target", "build"}
+
+// Rule detects a language in a directory holding a file that matches it
+type Rule struct {
+	Language string `json:"language"`
+	// Markers are exact file names, such as go.mod
+	Markers []string `json:"markers,omitempty"`
+	// Globs are file name patterns, such as *.csproj
+	Globs []string `json:"globs,omitempty"`
+	// Extensions are file name extensions, such as .tf
+	Extensions []string `json:"extensions,omitempty"`
+	// Content is a regular expression the matched file must contain, such as `kotlin` in a build.gradle
+	Content string `json:"content,omitempty"`
+	// Sources are the extensions of the language's source files, counted when Priority.BySourceFiles is set
+	Sources []string `json:"sources,omitempty"`
+}
+
+// Priority chooses the primary language of projects in which several languages are detected
+type Priority struct {
+	// Languages come first, in this order. Other languages follow in the order of the rules that detected them.
+	Languages []string `json:"languages,omitempty"`
+	// BySourceFiles makes the language with the most source files primary, with Languages breaking ties
+	BySourceFiles bool `json:"bySourceFiles,omitempty"`
+}
+
+// DEFAULT_RULES are tried after the configured rules, in order. Build files come before package.json, which is often
+// there for tooling only, and a Makefile alone marks a project of ANY language.
+var DEFAULT_RULES = []Rule{
+	{Language: KOTLIN, Markers: []string{"build.gradle.kts", "settings.gradle.kts"}, Sources: []string{".kt", ".kts"}},
+	{Language: KOTLIN, Markers: []string{"build.gradle"}, Content: `org\.jetbrains\.kotlin|kotlin\(`, Sources: []string{".kt", ".kts"}},
+	{Language: JAVA, Markers: []string{"pom.xml", "build.gradle", "settings.gradle"}, Sources: []string{".java"}},
+	{Language: SCALA, Markers: []string{"build.sbt"}, Sources: []string{".scala"}},
+	{Language: GO, Markers: []string{"go.mod"}, Sources: []string{".go"}},
+	{Language: RUST, Markers: []string{"Cargo.toml"}, Sources: []string{".rs"}},
+	{Language: CSHARP, Globs: []string{"*.csproj", "*.sln"}, Sources: []string{".cs"}},
+	{Language: CPP, Markers: []string{"CMakeLists.txt"}, Sources: []string{".cpp", ".cc", ".cxx", ".c", ".hpp", ".h"}},
+	{Language: ELIXIR, Markers: []string{"mix.exs"}, Sources: []string{".ex", ".exs"}},
+	{Language: RUBY, Markers: []string{"Gemfile"}, Sources: []string{".rb"}},
+	{Language: PYTHON, Markers: []string{"requirements.txt", "Pipfile", "poetry.toml", "pyproject.toml"}, Sources: []string{".py"}},
+	{Language: NODE, Markers: []string{"package.json"}, Sources: []string{".js", ".mjs", ".cjs", ".jsx", ".ts", ".tsx"}},
+	{Language: TERRAFORM, Extensions: []string{".tf"}, Sources: []string{".tf"}},
+	{Language: ANY, Markers: []string{"Makefile"}},
+}
+
+// InvalidRuleError is returned for a configured rule that cannot be used
+type InvalidRuleError struct {
+	Rule   Rule
+	Reason string
+}
+
+func (err *InvalidRuleError) Error() string {
+	return fmt.Sprintf("invalid languageRules entry for '%s' in config.json: %s", err.Rule.Language, err.Reason)
+}
+
+// Detector detects the languages of project directories with its rules
+type Detector struct {
+	rules    []Rule
+	content  []*regexp.Regexp
+	priority Priority
+}
+
+// NewDetector returns a detector trying rules before DEFAULT_RULES, which chooses the primary language by priority
+func NewDetector(rules []Rule, priority Priority) (*Detector, error) {
+	// Language names are case insensitive, and detected in lowercase
+	priority.Languages = slices.Clone(priority.Languages)
+	for i, languageName := range priority.Languages {
+		priority.Languages[i] = strings.ToLower(languageName)
+	}
+
+	detector := &Detector{priority: priority}
+	for _, rule := range append(slices.Clone(rules), DEFAULT_RULES...) {
+		rule.Language = strings.ToLower(rule.Language)
+		if rule.Language == "" {
+			return nil, &InvalidRuleError{Rule: rule, Reason: "no language"}
+		}
+		if len(rule.Markers)+len(rule.Globs)+len(rule.Extensions) == 0 {
+			return nil, &InvalidRuleError{Rule: rule, Reason: "no markers, globs or extensions"}
+		}
+		for _, extension := range append(slices.Clone(rule.Extensions), rule.Sources...) {
+			if !strings.HasPrefix(extension, ".") {
+				return nil, &InvalidRuleError{Rule: rule, Reason: fmt.Sprintf("extension %q does not start with '.'", extension)}
+			}
+		}
+		for _, glob := range rule.Globs {
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return nil, &InvalidRuleError{Rule: rule, Reason: fmt.Sprintf("glob %q: %v", glob, err)}
+			}
+		}
+
+		var content *regexp.Regexp
+		if rule.Content != "" {
+			compiled, err := regexp.Compile(rule.Content)
+			if err != nil {
+				return nil, &InvalidRuleError{Rule: rule, Reason: fmt.Sprintf("content %q: %v", rule.Content, err)}
+			}
+			content = compiled
+		}
+
+		detector.rules = append(detector.rules, rule)
+		detector.content = append(detector.content, content)
+	}
+	return detector, nil
+}
+
+// Detect returns the primary language of dir
+func (detector *Detector) Detect(dir string) (string, bool) {
+	languages := detector.DetectAll(dir)
+	if len(languages) == 0 {
+		return "", false
+	}
+	return languages[0], true
+}
+
+// DetectAll returns all the languages detected in dir, the primary one first. ANY is only returned on its own.
+func (detector *Detector) DetectAll(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var languages []string
+	for i, rule := range detector.rules {
+		if slices.Contains(languages, rule.Language) {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !rule.matchesName(entry.Name()) {
+				continue
+			}
+			if detector.content[i] == nil || contains(filepath.Join(dir, entry.Name()), detector.content[i]) {
+				languages = append(languages, rule.Language)
+				break
+			}
+		}
+	}
+
+	if len(languages) > 1 {
+		languages = slices.DeleteFunc(languages, func(languageName string) bool { return languageName == ANY })
+	}
+	if len(languages) > 1 {
+		detector.prioritize(dir, languages)
+	}
+	return languages
+}
+
+// prioritize orders languages, which are in the order of the rules that detected them, by the detector's priority
+func (detector *Detector) prioritize(dir string, languages []string) {
+	rank := func(languageName string) int {
+		if index := slices.Index(detector.priority.Languages, languageName); index != -1 {
+			return index
+		}
+		return len(detector.priority.Languages)
+	}
+
+	var sourceFiles map[string]int
+	if detector.priority.BySourceFiles {
+		sourceFiles = detector.countSourceFiles(dir, languages)
+	}
+
+	slices.SortStableFunc(languages, func(first string, second string) int {
+		if sourceFiles[first] != sourceFiles[second] {
+			return sourceFiles[second] - sourceFiles[first]
+		}
+		return rank(first) - rank(second)
+	})
+}
+
+// countSourceFiles counts the source files of languages under dir, up to MAX_COUNTED_FILES files
+func (detector *Detector) countSourceFiles(dir string, languages []string) map[string]int {
+	languagesBySource := map[string][]string{}
+	for _, rule := range detector.rules {
+		if !slices.Contains(languages, rule.Language) {
+			continue
+		}
+		for _, extension := range rule.Sources {
+			if !slices.Contains(languagesBySource[extension], rule.Language) {
+				languagesBySource[extension] = append(languagesBySource[extension], rule.Language)
+			}
+		}
+	}
+
+	counts := map[string]int{}
+	files := 0
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != dir && slices.Contains(SKIPPED_DIRS, entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if files++; files > MAX_COUNTED_FILES {
+			return filepath.SkipAll
+		}
+		for _, languageName := range languagesBySource[filepath.Ext(entry.Name())] {
+			counts[languageName]++
+		}
+		return nil
+	})
+	return counts
+}
+
+// IsMarker tells whether a file with the given name may change the language detected in its directory
+func (detector *Detector) IsMarker(fileName string) bool {
+	for _, rule := range detector.rules {
+		if rule.matchesName(fileName) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule Rule) matchesName(fileName string) bool {
+	if slices.Contains(rule.Markers, fileName) || slices.Contains(rule.Extensions, filepath.Ext(fileName)) {
+		return true
+	}
+	for _, glob := range rule.Globs {
+		if matched, _ := filepath.Match(glob, fileName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// contains tells whether the beginning of a file matches content
+func contains(path string, content *regexp.Regexp) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MAX_CONTENT_SIZE))
+	return err == nil && content.Match(data)
+}
@@ -0,0 +1,133 @@
+package language
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// projectDir creates a directory holding files with the given names and contents
+func projectDir(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDetect_DefaultRules(t *testing.T) {
+	detector, err := NewDetector(nil, Priority{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		files    map[string]string
+		expected string
+	}{
+		{map[string]string{"go.mod": "", "package.json": "", "Makefile": ""}, GO},
+		{map[string]string{"requirements.txt": "", "package.json": ""}, PYTHON},
+		{map[string]string{"build.gradle": "plugins { id 'java' }"}, JAVA},
+		{map[string]string{"build.gradle": "plugins { kotlin(\"jvm\") }"}, KOTLIN},
+		{map[string]string{"settings.gradle.kts": "", "pom.xml": ""}, KOTLIN},
+		{map[string]string{"Api.csproj": ""}, CSHARP},
+		{map[string]string{"main.tf": "", "README.md": ""}, TERRAFORM},
+		{map[string]string{"Makefile": ""}, ANY},
+	} {
+		dir := projectDir(t, test.files)
+		if detected, found := detector.Detect(dir); !found || detected != test.expected {
+			t.Errorf("Expected %s for %v, got %q", test.expected, test.files, detected)
+		}
+	}
+
+	if detected, found := detector.Detect(projectDir(t, map[string]string{"README.md": ""})); found {
+		t.Errorf("Expected no language for a README, got %s", detected)
+	}
+}
+
+func TestDetect_ConfiguredRules(t *testing.T) {
+	detector, err := NewDetector([]Rule{
+		{Language: "bazel", Markers: []string{"BUILD.bazel"}},
+		{Language: "deno", Markers: []string{"package.json"}, Content: `"deno"`},
+	}, Priority{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if detected, _ := detector.Detect(projectDir(t, map[string]string{"go.mod": "", "BUILD.bazel": ""})); detected != "bazel" {
+		t.Errorf("Expected configured rules to come first, got %s", detected)
+	}
+	if detected, _ := detector.Detect(projectDir(t, map[string]string{"package.json": `{"deno": true}`})); detected != "deno" {
+		t.Errorf("Expected the content check to match, got %s", detected)
+	}
+	if detected, _ := detector.Detect(projectDir(t, map[string]string{"package.json": `{}`})); detected != NODE {
+		t.Errorf("Expected the default rules when the content does not match, got %s", detected)
+	}
+
+	if !detector.IsMarker("BUILD.bazel") || !detector.IsMarker("Web.sln") || !detector.IsMarker("vars.tf") || detector.IsMarker("main.go") {
+		t.Errorf("Unexpected markers")
+	}
+}
+
+func TestDetect_LowercasesLanguages(t *testing.T) {
+	detector, err := NewDetector([]Rule{{Language: "Zig", Markers: []string{"build.zig"}}}, Priority{Languages: []string{"Zig"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := projectDir(t, map[string]string{"build.zig": "", "go.mod": ""})
+	if languages := detector.DetectAll(dir); !reflect.DeepEqual(languages, []string{"zig", GO}) {
+		t.Errorf("Expected the configured language in lowercase, got %v", languages)
+	}
+}
+
+func TestNewDetector_InvalidRules(t *testing.T) {
+	for _, rule := range []Rule{
+		{Markers: []string{"BUILD"}},
+		{Language: "bazel"},
+		{Language: "terraform", Extensions: []string{"tf"}},
+		{Language: "csharp", Globs: []string{"[*.csproj"}},
+		{Language: "deno", Markers: []string{"package.json"}, Content: "(deno"},
+	} {
+		var invalidRuleError *InvalidRuleError
+		if _, err := NewDetector([]Rule{rule}, Priority{}); !errors.As(err, &invalidRuleError) {
+			t.Errorf("Expected %+v to be invalid, got %v", rule, err)
+		}
+	}
+}
+
+func TestDetectAll_Priority(t *testing.T) {
+	dir := projectDir(t, map[string]string{"go.mod": "", "package.json": "", "Makefile": "", "main.go": "", "a.js": "", "b.ts": ""})
+	os.MkdirAll(filepath.Join(dir, "node_modules", "dep"), 0755)
+	for _, name := range []string{"c.js", "d.js", "e.js"} {
+		os.WriteFile(filepath.Join(dir, "node_modules", "dep", name), nil, 0644)
+	}
+
+	for _, test := range []struct {
+		priority Priority
+		expected []string
+	}{
+		{Priority{}, []string{GO, NODE}},
+		{Priority{Languages: []string{NODE}}, []string{NODE, GO}},
+		{Priority{BySourceFiles: true}, []string{NODE, GO}},
+		{Priority{Languages: []string{PYTHON, GO}, BySourceFiles: true}, []string{NODE, GO}},
+	} {
+		detector, err := NewDetector(nil, test.priority)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if languages := detector.DetectAll(dir); !reflect.DeepEqual(languages, test.expected) {
+			t.Errorf("Expected %v with %+v, got %v", test.expected, test.priority, languages)
+		}
+	}
+
+	os.Remove(filepath.Join(dir, "b.ts"))
+	detector, _ := NewDetector(nil, Priority{Languages: []string{NODE}, BySourceFiles: true})
+	if languages := detector.DetectAll(dir); !reflect.DeepEqual(languages, []string{NODE, GO}) {
+		t.Errorf("Expected the priority to break ties between source file counts, got %v", languages)
+	}
+}
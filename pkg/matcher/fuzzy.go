@@ -0,0 +1,148 @@
+package matcher
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+	bonusBoundary     = 8
+	bonusPathSegment  = 10
+	bonusCamelCase    = 7
+	bonusConsecutive  = 6
+	// The bonus of the first pattern character counts double, favoring matches that start a word
+	bonusFirstCharMultiplier = 2
+	lengthPenaltyFactor      = 8
+
+	noMatch = -1 << 30
+)
+
+// Ranked is an item that matched a fuzzy query, along with how well it matched.
+type Ranked[T any] struct {
+	Item  T
+	Score int
+}
+
+// RankItems keeps the elements matching every query term and orders them by score, best first.
+// Each term is matched fuzzily (case-insensitive, in order, not necessarily consecutive) against
+// the element's matchable values, and scores the best of them. Elements with equal scores keep
+// their index order.
+func RankItems[T Matchable](elements []T, terms []string) []Ranked[T] {
+	var result []Ranked[T]
+
+	for _, element := range elements {
+		if score, matched := scoreElement(element.Matchable(), terms); matched {
+			result = append(result, Ranked[T]{Item: element, Score: score})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Score > result[j].Score
+	})
+
+	return result
+}
+
+func scoreElement(matchableValues []string, terms []string) (int, bool) {
+	total := 0
+	for _, term := range terms {
+		best := noMatch
+		for _, value := range matchableValues {
+			if score, matched := Score(term, value); matched && score > best {
+				best = score
+			}
+		}
+		if best == noMatch {
+			return 0, false
+		}
+		total += best
+	}
+	return total, true
+}
+
+// Score fuzzily matches pattern against text.
+// Every matched character scores, with bonuses for characters that start a word or a path segment
+// and for runs of consecutive characters, and penalties for gaps between them and for long texts.
+func Score(pattern string, text string) (int, bool) {
+	patternRunes := []rune(strings.ToLower(pattern))
+	textRunes := []rune(text)
+	lowerText := []rune(strings.ToLower(text))
+	if len(lowerText) != len(textRunes) {
+		lowerText = textRunes
+	}
+
+	m, n := len(patternRunes), len(textRunes)
+	if m == 0 {
+		return 0, true
+	}
+	if m > n {
+		return 0, false
+	}
+
+	// previous[j] / current[j] hold the best score of matching the pattern up to the current
+	// character, with that character matched at text position j
+	previous := make([]int, n)
+	current := make([]int, n)
+
+	for i := 0; i < m; i++ {
+		gapScore := noMatch
+		for j := 0; j < n; j++ {
+			if i > 0 && j >= 2 {
+				gapScore = max(gapScore+scoreGapExtension, previous[j-2]+scoreGapStart)
+			}
+
+			current[j] = noMatch
+			if lowerText[j] != patternRunes[i] {
+				continue
+			}
+
+			bonus := charBonus(textRunes, j)
+			if i == 0 {
+				current[j] = scoreMatch + bonus*bonusFirstCharMultiplier
+				continue
+			}
+
+			best := gapScore
+			if j >= 1 && previous[j-1] > noMatch {
+				best = max(best, previous[j-1]+bonusConsecutive)
+			}
+			if best > noMatch/2 {
+				current[j] = best + scoreMatch + bonus
+			}
+		}
+		previous, current = current, previous
+	}
+
+	best := noMatch
+	for _, score := range previous {
+		best = max(best, score)
+	}
+	if best <= noMatch/2 {
+		return 0, false
+	}
+
+	return best - n/lengthPenaltyFactor, true
+}
+
+func charBonus(text []rune, position int) int {
+	if position == 0 {
+		return bonusBoundary
+	}
+
+	previous, current := text[position-1], text[position]
+	switch {
+	case previous == '/':
+		return bonusPathSegment
+	case previous == '-' || previous == '_' || previous == '.' || previous == ' ':
+		return bonusBoundary
+	case unicode.IsLower(previous) && unicode.IsUpper(current):
+		return bonusCamelCase
+	case !unicode.IsDigit(previous) && unicode.IsDigit(current):
+		return bonusCamelCase
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package matcher
+
+import (
+	"testing"
+)
+
+type testItem []string
+
+func (item testItem) Matchable() []string {
+	return item
+}
+
+func TestScore_NoMatch(t *testing.T) {
+	for _, text := range []string{"", "ap", "pia", "a-p"} {
+		if _, matched := Score("api", text); matched {
+			t.Errorf("Did not expect 'api' to match %q", text)
+		}
+	}
+}
+
+func TestScore_Preferences(t *testing.T) {
+	cases := []struct {
+		pattern, better, worse string
+		reason                 string
+	}{
+		{"api", "api", "a-p-i", "consecutive characters"},
+		{"api", "services/api", "services/rapid", "path segment start"},
+		{"gw", "api-gateway-web", "bigwig", "word boundaries"},
+		{"rs", "RepoServer", "parsers", "camel case boundaries"},
+		{"api", "api", "api-gateway-legacy-backend", "shorter names"},
+		{"API", "api", "my-rapid", "case-insensitive matching"},
+	}
+
+	for _, c := range cases {
+		better, matchedBetter := Score(c.pattern, c.better)
+		worse, matchedWorse := Score(c.pattern, c.worse)
+		if !matchedBetter || !matchedWorse {
+			t.Errorf("Expected %q to match both %q and %q", c.pattern, c.better, c.worse)
+			continue
+		}
+		if better <= worse {
+			t.Errorf("Expected %q to score %q (%d) above %q (%d) due to %s", c.pattern, c.better, better, c.worse, worse, c.reason)
+		}
+	}
+}
+
+func TestRankItems(t *testing.T) {
+	items := []testItem{
+		{"legacy/capital-ui"},
+		{"platform/api"},
+		{"unrelated"},
+		{"platform/api-gateway", "gw"},
+	}
+
+	ranked := RankItems(items, []string{"api"})
+
+	if len(ranked) != 3 {
+		t.Fatalf("Expected 3 matches, got %v", ranked)
+	}
+	if ranked[0].Item[0] != "platform/api" || ranked[1].Item[0] != "platform/api-gateway" || ranked[2].Item[0] != "legacy/capital-ui" {
+		t.Errorf("Unexpected order: %v", ranked)
+	}
+	if ranked[0].Score < ranked[1].Score || ranked[1].Score < ranked[2].Score {
+		t.Errorf("Expected scores to be descending: %v", ranked)
+	}
+
+	// Every term has to match, against any of the matchable values
+	ranked = RankItems(items, []string{"platform", "gw"})
+	if len(ranked) != 1 || ranked[0].Item[0] != "platform/api-gateway" {
+		t.Errorf("Expected only platform/api-gateway to match, got %v", ranked)
+	}
+
+	// No terms match everything, in index order
+	ranked = RankItems(items, nil)
+	if len(ranked) != len(items) || ranked[0].Item[0] != "legacy/capital-ui" {
+		t.Errorf("Expected all items in index order, got %v", ranked)
+	}
+}
@@ -1,12 +1,26 @@
 package matcher
 
 import (
-	"os"
+	"fmt"
 	"regexp"
 	"strings"
 )
 
-func BuildPattern(args []string) *regexp.Regexp {
+// InvalidPatternError is returned when the filters do not form a valid regular expression.
+type InvalidPatternError struct {
+	Pattern string
+	Err     error
+}
+
+func (err *InvalidPatternError) Error() string {
+	return fmt.Sprintf("invalid pattern '%s': %v", err.Pattern, err.Err)
+}
+
+func (err *InvalidPatternError) Unwrap() error {
+	return err.Err
+}
+
+func BuildPattern(args []string) (*regexp.Regexp, error) {
 	var searchPattern string
 
 	if len(args) == 0 {
@@ -17,10 +31,10 @@ func BuildPattern(args []string) *regexp.Regexp {
 
 	regexPattern, regexError := regexp.Compile(searchPattern)
 	if regexError != nil {
-		os.Exit(3)
+		return nil, &InvalidPatternError{Pattern: strings.Join(args, " "), Err: regexError}
 	}
 
-	return regexPattern
+	return regexPattern, nil
 }
 
 type Matchable interface {
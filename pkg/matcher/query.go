@@ -0,0 +1,129 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Query is a search split into free text terms and field filters, e.g.
+// `api type:gitlab -name:legacy` has the term `api` and two filters.
+type Query struct {
+	Terms   []string
+	Filters []FieldFilter
+}
+
+// FieldFilter keeps (or, when negated, drops) the items whose field contains the value.
+type FieldFilter struct {
+	Field   string
+	Value   string
+	Negated bool
+}
+
+// Field holds the values a filter is matched against. Exact fields must equal the filter value,
+// all others only have to contain it. Both ignore case.
+type Field struct {
+	Values []string
+	Exact  bool
+}
+
+// Queryable items expose named fields to filter on.
+type Queryable interface {
+	Matchable
+	Fields() map[string]Field
+}
+
+// UnknownFieldError is returned when a filter uses a field the items do not have.
+type UnknownFieldError struct {
+	Field           string
+	SupportedFields []string
+}
+
+func (err *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field '%s' (supported fields: %s)", err.Field, strings.Join(err.SupportedFields, ", "))
+}
+
+var FIELD_FILTER_REGEX = regexp.MustCompile(`^(-?)([a-z]+):(.+)$`)
+
+// NOT_A_FILTER_VALUE_REGEX matches the rest of URLs (https://host/...) and host:port terms, which are text, not filters
+var NOT_A_FILTER_VALUE_REGEX = regexp.MustCompile(`^(//|\d+(/|$))`)
+
+// IsFieldFilter tells whether arg is a field filter rather than a free text term.
+func IsFieldFilter(arg string) bool {
+	_, isFilter := parseFieldFilter(arg)
+	return isFilter
+}
+
+func parseFieldFilter(arg string) (FieldFilter, bool) {
+	parts := FIELD_FILTER_REGEX.FindStringSubmatch(arg)
+	if parts == nil || NOT_A_FILTER_VALUE_REGEX.MatchString(parts[3]) {
+		return FieldFilter{}, false
+	}
+	return FieldFilter{Field: parts[2], Value: parts[3], Negated: parts[1] == "-"}, true
+}
+
+func ParseQuery(args []string) Query {
+	var query Query
+	for _, arg := range args {
+		if filter, isFilter := parseFieldFilter(arg); isFilter {
+			query.Filters = append(query.Filters, filter)
+		} else {
+			query.Terms = append(query.Terms, arg)
+		}
+	}
+	return query
+}
+
+// FilterItems keeps the elements matching all of the query's filters.
+// It fails with an *UnknownFieldError if a filter uses a field items of type T do not have, even when there are none.
+func FilterItems[T Queryable](elements []T, filters []FieldFilter) ([]T, error) {
+	if len(filters) == 0 {
+		return elements, nil
+	}
+
+	var item T
+	supportedFields := item.Fields()
+	for _, filter := range filters {
+		if _, supported := supportedFields[filter.Field]; !supported {
+			return nil, &UnknownFieldError{Field: filter.Field, SupportedFields: fieldNames(supportedFields)}
+		}
+	}
+
+	var result []T
+	for _, element := range elements {
+		fields := element.Fields()
+		matchesAll := true
+		for _, filter := range filters {
+			if fieldMatches(fields[filter.Field], filter.Value) == filter.Negated {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			result = append(result, element)
+		}
+	}
+
+	return result, nil
+}
+
+func fieldMatches(field Field, value string) bool {
+	value = strings.ToLower(value)
+	for _, fieldValue := range field.Values {
+		fieldValue = strings.ToLower(fieldValue)
+		if fieldValue == value || (!field.Exact && strings.Contains(fieldValue, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldNames(fields map[string]Field) []string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
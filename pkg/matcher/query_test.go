@@ -0,0 +1,75 @@
+package matcher
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testRecord struct {
+	name string
+	kind string
+}
+
+func (record testRecord) Matchable() []string {
+	return []string{record.name}
+}
+
+func (record testRecord) Fields() map[string]Field {
+	return map[string]Field{
+		"name": {Values: []string{record.name}},
+		"type": {Values: []string{record.kind}, Exact: true},
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	query := ParseQuery([]string{"api", "type:gitlab", "-name:legacy", "remote:https://github.com/acme", "C:",
+		"https://github.com/acme/api", "localhost:8080", "git.acme.com:7999/api"})
+
+	expected := Query{
+		Terms: []string{"api", "C:", "https://github.com/acme/api", "localhost:8080", "git.acme.com:7999/api"},
+		Filters: []FieldFilter{
+			{Field: "type", Value: "gitlab"},
+			{Field: "name", Value: "legacy", Negated: true},
+			{Field: "remote", Value: "https://github.com/acme"},
+		},
+	}
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("Expected %v, got %v", expected, query)
+	}
+}
+
+func TestFilterItems(t *testing.T) {
+	records := []testRecord{
+		{"api-gateway", "gitlab"},
+		{"legacy-api", "gitlab"},
+		{"api-docs", "github"},
+		{"website", "GitLab"},
+	}
+
+	filtered, err := FilterItems(records, ParseQuery([]string{"type:gitlab", "-name:LEGACY"}).Filters)
+	if err != nil {
+		t.Fatalf("FilterItems failed: %v", err)
+	}
+	expected := []testRecord{{"api-gateway", "gitlab"}, {"website", "GitLab"}}
+	if !reflect.DeepEqual(filtered, expected) {
+		t.Errorf("Expected %v, got %v", expected, filtered)
+	}
+
+	// Exact fields do not match partial values
+	filtered, _ = FilterItems(records, ParseQuery([]string{"type:git"}).Filters)
+	if len(filtered) != 0 {
+		t.Errorf("Expected no partial matches on an exact field, got %v", filtered)
+	}
+
+	_, err = FilterItems(records, ParseQuery([]string{"lang:go"}).Filters)
+	var unknownFieldError *UnknownFieldError
+	if !errors.As(err, &unknownFieldError) || unknownFieldError.Field != "lang" {
+		t.Errorf("Expected an UnknownFieldError for an unknown field, got %v", err)
+	}
+
+	_, err = FilterItems([]testRecord{}, ParseQuery([]string{"lnag:go"}).Filters)
+	if !errors.As(err, &unknownFieldError) || unknownFieldError.Field != "lnag" {
+		t.Errorf("Expected an UnknownFieldError for an unknown field without items, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package picker
+
+import "unicode/utf8"
+
+type keyCode int
+
+const (
+	KEY_CHAR keyCode = iota
+	KEY_CTRL
+	KEY_ENTER
+	KEY_BACKSPACE
+	KEY_ESCAPE
+	KEY_UP
+	KEY_DOWN
+	KEY_PAGE_UP
+	KEY_PAGE_DOWN
+)
+
+// key is a single key press. char holds the typed character for KEY_CHAR, and the (lower case) letter for KEY_CTRL.
+type key struct {
+	code keyCode
+	char rune
+}
+
+// decodeKeys splits raw terminal input into key presses. Escape sequences that are not recognized are dropped.
+func decodeKeys(input []byte) []key {
+	var keys []key
+	for len(input) > 0 {
+		b := input[0]
+		switch {
+		case b == 0x1b && len(input) > 1 && (input[1] == '[' || input[1] == 'O'):
+			length, pressed, recognized := decodeEscapeSequence(input)
+			if recognized {
+				keys = append(keys, pressed)
+			}
+			input = input[length:]
+		case b == 0x1b:
+			keys = append(keys, key{code: KEY_ESCAPE})
+			input = input[1:]
+		case b == '\r' || b == '\n':
+			keys = append(keys, key{code: KEY_ENTER})
+			input = input[1:]
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, key{code: KEY_BACKSPACE})
+			input = input[1:]
+		case b < 0x20:
+			keys = append(keys, key{code: KEY_CTRL, char: rune('a' + b - 1)})
+			input = input[1:]
+		default:
+			char, size := utf8.DecodeRune(input)
+			keys = append(keys, key{code: KEY_CHAR, char: char})
+			input = input[size:]
+		}
+	}
+	return keys
+}
+
+// decodeEscapeSequence decodes the CSI (`ESC [`) or SS3 (`ESC O`) sequence input starts with, returning its length
+func decodeEscapeSequence(input []byte) (int, key, bool) {
+	end := 2
+	for end < len(input) && (input[end] < 0x40 || input[end] > 0x7e) {
+		end++
+	}
+	if end == len(input) {
+		return len(input), key{}, false
+	}
+
+	switch string(input[2 : end+1]) {
+	case "A":
+		return end + 1, key{code: KEY_UP}, true
+	case "B":
+		return end + 1, key{code: KEY_DOWN}, true
+	case "5~":
+		return end + 1, key{code: KEY_PAGE_UP}, true
+	case "6~":
+		return end + 1, key{code: KEY_PAGE_DOWN}, true
+	default:
+		return end + 1, key{}, false
+	}
+}
@@ -0,0 +1,266 @@
+// Package picker is griffin's built-in interactive picker: a full screen terminal UI that filters the indexed
+// repositories or projects as you type and previews the highlighted one, so the shell integration does not
+// depend on fzf and tree being installed.
+package picker
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"strings"
+
+	"ronkitay.com/griffin/pkg/griffin"
+)
+
+// Action is what the user chose to do with the selected item
+type Action int
+
+const (
+	ACTION_CD Action = iota
+	ACTION_OPEN_IDE
+	ACTION_OPEN_ALTERNATIVE_IDE
+	ACTION_OPEN_WEB
+)
+
+// ACTION_NAMES describe the actions in the help line
+var ACTION_NAMES = map[Action]string{
+	ACTION_CD:                   "cd",
+	ACTION_OPEN_IDE:             "IDE",
+	ACTION_OPEN_ALTERNATIVE_IDE: "alternative IDE",
+	ACTION_OPEN_WEB:             "web",
+}
+
+// ErrCancelled is returned when the picker is closed without selecting anything
+var ErrCancelled = errors.New("nothing was selected")
+
+// Item is a single line of the picker
+type Item struct {
+	Path    string
+	Name    string
+	Details string
+}
+
+// Selection is the item the user picked, and what to do with it
+type Selection struct {
+	Action Action
+	Item   Item
+}
+
+// Options control how the picker starts and what Enter does
+type Options struct {
+	// Query is the initial query, which the user can edit
+	Query []string
+	// SelectSingle returns the only item matching the initial query without showing the picker, like `fzf -1`
+	SelectSingle bool
+	// EnterAction is the action of the Enter key (ACTION_CD by default)
+	EnterAction Action
+	// PreviewCommand replaces the built-in preview with the output of a shell command, in which {} is the path
+	PreviewCommand string
+}
+
+// Source returns the items matching a query, best match first
+type Source func(query []string) ([]Item, error)
+
+// RepoSource searches the repository index of client
+func RepoSource(client *griffin.Client, options griffin.RepoSearchOptions) Source {
+	return func(query []string) ([]Item, error) {
+		repos, err := client.FindRepos(options, query)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, repo := range repos {
+			items = append(items, Item{Path: repo.Item.ToString(), Name: repo.Item.FullName, Details: repo.Item.Type})
+		}
+		return items, nil
+	}
+}
+
+// ProjectSource searches the project index of client
+func ProjectSource(client *griffin.Client, options griffin.SearchOptions) Source {
+	return func(query []string) ([]Item, error) {
+		projects, err := client.FindProjects(options, query)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []Item
+		for _, project := range projects {
+			items = append(items, Item{Path: project.Item.ToString(), Name: project.Item.FullName, Details: strings.Join(project.Item.Languages, ",")})
+		}
+		return items, nil
+	}
+}
+
+// Pick shows the picker on the controlling terminal and returns the user's selection.
+// The terminal is used directly, leaving stdout free for the caller to print the result on.
+func Pick(source Source, options Options) (Selection, error) {
+	if options.SelectSingle {
+		items, err := source(options.Query)
+		if err != nil {
+			return Selection{}, err
+		}
+		if len(items) == 1 {
+			return Selection{Action: options.EnterAction, Item: items[0]}, nil
+		}
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return Selection{}, err
+	}
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return Selection{}, err
+	}
+	defer restore()
+
+	tty.WriteString(ENTER_ALTERNATE_SCREEN)
+	defer tty.WriteString(LEAVE_ALTERNATE_SCREEN)
+
+	resizes := make(chan os.Signal, 1)
+	if len(resizeSignals) > 0 {
+		signal.Notify(resizes, resizeSignals...)
+		defer signal.Stop(resizes)
+	}
+
+	input := make(chan []byte)
+	go readInput(tty, input)
+
+	state := newState(source, strings.Join(options.Query, " "), options.EnterAction)
+	state.previewCommand = options.PreviewCommand
+	for {
+		width, height, err := terminalSize(fd)
+		if err != nil || width == 0 || height == 0 {
+			width, height = 80, 24
+		}
+		tty.WriteString(state.render(width, height))
+
+		select {
+		case <-resizes:
+		case chunk, open := <-input:
+			if !open {
+				return Selection{}, ErrCancelled
+			}
+			for _, pressed := range decodeKeys(chunk) {
+				if selection, done := state.handle(pressed, height); done {
+					if selection == nil {
+						return Selection{}, ErrCancelled
+					}
+					return *selection, nil
+				}
+			}
+		}
+	}
+}
+
+func readInput(tty *os.File, input chan<- []byte) {
+	defer close(input)
+	buffer := make([]byte, 256)
+	for {
+		n, err := tty.Read(buffer)
+		if err != nil {
+			return
+		}
+		input <- append([]byte(nil), buffer[:n]...)
+	}
+}
+
+// state is everything shown by the picker, updated by key presses
+type state struct {
+	source      Source
+	enterAction Action
+	query       []rune
+	items       []Item
+	err         error
+	cursor      int
+	offset      int
+	previews    map[string][]string
+	// previewCommand is Options.PreviewCommand
+	previewCommand string
+}
+
+func newState(source Source, query string, enterAction Action) *state {
+	state := &state{source: source, enterAction: enterAction, query: []rune(query), previews: map[string][]string{}}
+	state.refresh()
+	return state
+}
+
+// refresh runs the query again. An invalid query (often one that is still being typed) keeps the previous results.
+func (state *state) refresh() {
+	items, err := state.source(strings.Fields(string(state.query)))
+	state.err = err
+	if err != nil {
+		return
+	}
+	state.items = items
+	state.cursor, state.offset = 0, 0
+}
+
+// handle applies a key press. done is set once the picker should close, with a nil selection if it was cancelled.
+func (state *state) handle(pressed key, height int) (selection *Selection, done bool) {
+	switch pressed.code {
+	case KEY_CHAR:
+		state.query = append(state.query, pressed.char)
+		state.refresh()
+	case KEY_BACKSPACE:
+		if len(state.query) > 0 {
+			state.query = state.query[:len(state.query)-1]
+			state.refresh()
+		}
+	case KEY_UP:
+		state.move(-1)
+	case KEY_DOWN:
+		state.move(1)
+	case KEY_PAGE_UP:
+		state.move(-listHeight(height))
+	case KEY_PAGE_DOWN:
+		state.move(listHeight(height))
+	case KEY_ESCAPE:
+		return nil, true
+	case KEY_ENTER:
+		return state.selected(state.enterAction)
+	case KEY_CTRL:
+		switch pressed.char {
+		case 'c', 'g', 'd':
+			return nil, true
+		case 'p':
+			state.move(-1)
+		case 'n':
+			state.move(1)
+		case 'u':
+			state.query = nil
+			state.refresh()
+		case 'w':
+			state.query = []rune(deleteLastWord(string(state.query)))
+			state.refresh()
+		case 'o':
+			return state.selected(ACTION_OPEN_IDE)
+		case 'a':
+			return state.selected(ACTION_OPEN_ALTERNATIVE_IDE)
+		case 'b':
+			return state.selected(ACTION_OPEN_WEB)
+		}
+	}
+	return nil, false
+}
+
+func (state *state) selected(action Action) (*Selection, bool) {
+	if len(state.items) == 0 {
+		return nil, false
+	}
+	return &Selection{Action: action, Item: state.items[state.cursor]}, true
+}
+
+func (state *state) move(delta int) {
+	state.cursor = max(0, min(state.cursor+delta, len(state.items)-1))
+}
+
+func deleteLastWord(query string) string {
+	trimmed := strings.TrimRight(query, " ")
+	return trimmed[:strings.LastIndex(trimmed, " ")+1]
+}
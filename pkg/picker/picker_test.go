@@ -0,0 +1,174 @@
+package picker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"ronkitay.com/griffin/pkg/matcher"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	input := []byte("aé\x1b[A\x1b[B\x1bOA\x1b[5~\x1b[1;5C\r\x7f\x0f\x1b")
+
+	expected := []key{
+		{code: KEY_CHAR, char: 'a'},
+		{code: KEY_CHAR, char: 'é'},
+		{code: KEY_UP},
+		{code: KEY_DOWN},
+		{code: KEY_UP},
+		{code: KEY_PAGE_UP},
+		// ctrl-right is not used, so it is dropped
+		{code: KEY_ENTER},
+		{code: KEY_BACKSPACE},
+		{code: KEY_CTRL, char: 'o'},
+		{code: KEY_ESCAPE},
+	}
+	if keys := decodeKeys(input); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Unexpected keys.\nExpected: %v\nGot:      %v", expected, keys)
+	}
+}
+
+type namedItem struct {
+	Item
+}
+
+func (item namedItem) Matchable() []string {
+	return []string{item.Name}
+}
+
+// itemSource fuzzy matches the items by name, like the index sources do
+func itemSource(names ...string) Source {
+	var all []namedItem
+	for _, name := range names {
+		all = append(all, namedItem{Item{Path: "/src/" + name, Name: name}})
+	}
+	return func(query []string) ([]Item, error) {
+		var items []Item
+		for _, ranked := range matcher.RankItems(all, query) {
+			items = append(items, ranked.Item.Item)
+		}
+		return items, nil
+	}
+}
+
+func typeText(state *state, text string) {
+	for _, char := range text {
+		state.handle(key{code: KEY_CHAR, char: char}, 24)
+	}
+}
+
+func TestState_FiltersAsYouType(t *testing.T) {
+	state := newState(itemSource("griffin", "gateway", "website"), "", ACTION_CD)
+	if len(state.items) != 3 {
+		t.Fatalf("Expected all items without a query, got %v", state.items)
+	}
+
+	typeText(state, "grf")
+	if len(state.items) != 1 || state.items[0].Name != "griffin" {
+		t.Errorf("Expected only griffin to match 'grf', got %v", state.items)
+	}
+
+	state.handle(key{code: KEY_BACKSPACE}, 24)
+	state.handle(key{code: KEY_BACKSPACE}, 24)
+	if len(state.items) != 2 {
+		t.Errorf("Expected griffin and gateway to match 'g', got %v", state.items)
+	}
+
+	state.handle(key{code: KEY_CTRL, char: 'u'}, 24)
+	if len(state.query) != 0 || len(state.items) != 3 {
+		t.Errorf("Expected ctrl-u to clear the query, got %q with %v", string(state.query), state.items)
+	}
+}
+
+func TestState_Actions(t *testing.T) {
+	state := newState(itemSource("griffin", "gateway", "website"), "", ACTION_OPEN_IDE)
+
+	state.handle(key{code: KEY_DOWN}, 24)
+	state.handle(key{code: KEY_DOWN}, 24)
+	state.handle(key{code: KEY_DOWN}, 24)
+	selection, done := state.handle(key{code: KEY_ENTER}, 24)
+	if !done || selection == nil || selection.Action != ACTION_OPEN_IDE || selection.Item.Name != "website" {
+		t.Errorf("Expected Enter to open the last item in the IDE, got %v", selection)
+	}
+
+	selection, done = state.handle(key{code: KEY_CTRL, char: 'b'}, 24)
+	if !done || selection == nil || selection.Action != ACTION_OPEN_WEB {
+		t.Errorf("Expected ctrl-b to open the web page, got %v", selection)
+	}
+
+	if selection, done = state.handle(key{code: KEY_ESCAPE}, 24); !done || selection != nil {
+		t.Errorf("Expected Escape to cancel, got %v", selection)
+	}
+
+	typeText(state, "nothing matches this")
+	if selection, done = state.handle(key{code: KEY_ENTER}, 24); done {
+		t.Errorf("Expected Enter to be ignored without results, got %v", selection)
+	}
+}
+
+func TestRender_FitsTheTerminal(t *testing.T) {
+	state := newState(itemSource("griffin", "a-repository-with-a-name-that-is-much-longer-than-the-terminal"), "", ACTION_CD)
+
+	screen := state.render(60, 10)
+	lines := strings.Split(screen, "\r\n")
+	if len(lines) != 10 {
+		t.Errorf("Expected 10 lines, got %d", len(lines))
+	}
+	if expected := "  a-repository-with-a-name-that-is-much-longer-than-the-ter…"; !strings.HasPrefix(lines[3], expected) {
+		t.Errorf("Expected long names to be cut, got %q", lines[3])
+	}
+}
+
+func TestBuildPreview(t *testing.T) {
+	repoDir := t.TempDir()
+	cmd := exec.Command("git", "init", "-b", "main")
+	cmd.Dir = repoDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, output)
+	}
+	cmd = exec.Command("git", "remote", "add", "origin", "git@github.com:acme/griffin.git")
+	cmd.Dir = repoDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git remote add failed: %v\n%s", err, output)
+	}
+
+	os.MkdirAll(filepath.Join(repoDir, "cmd", "griffin", "deep"), 0755)
+	os.WriteFile(filepath.Join(repoDir, "cmd", "griffin", "main.go"), nil, 0644)
+	os.WriteFile(filepath.Join(repoDir, "go.mod"), nil, 0644)
+
+	expected := []string{
+		repoDir,
+		"branch: main",
+		"remote: origin git@github.com:acme/griffin.git",
+		"",
+		"├── cmd/",
+		"│   └── griffin/",
+		"└── go.mod",
+	}
+	if preview := buildPreview(repoDir); !reflect.DeepEqual(preview, expected) {
+		t.Errorf("Unexpected preview.\nExpected: %q\nGot:      %q", expected, preview)
+	}
+
+	// A project inside the repository shows the repository's branch
+	if preview := buildPreview(filepath.Join(repoDir, "cmd")); len(preview) < 2 || preview[1] != "branch: main" {
+		t.Errorf("Expected the branch of the enclosing repository, got %q", preview)
+	}
+}
+
+func TestCommandPreview(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "it's here")
+	os.Mkdir(path, 0755)
+
+	expected := []string{path, "colored    tab"}
+	if preview := commandPreview(`echo {}; printf '\033[1;32mcolored\033[0m\ttab\n'`, path); !reflect.DeepEqual(preview, expected) {
+		t.Errorf("Expected %q, got %q", expected, preview)
+	}
+
+	if preview := commandPreview("exit 3", path); len(preview) == 0 || !strings.HasPrefix(preview[len(preview)-1], "preview failed") {
+		t.Errorf("Expected a failing preview command to be reported, got %q", preview)
+	}
+}
@@ -0,0 +1,232 @@
+package picker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"ronkitay.com/griffin/pkg/repoindex"
+	"ronkitay.com/griffin/pkg/terminal"
+)
+
+const (
+	ENTER_ALTERNATE_SCREEN = "\033[?1049h\033[H"
+	LEAVE_ALTERNATE_SCREEN = "\033[?1049l"
+	CURSOR_HOME            = "\033[H"
+	CLEAR_LINE             = "\033[K"
+
+	// MIN_PREVIEW_WIDTH is the narrowest terminal the preview pane is shown in
+	MIN_PREVIEW_WIDTH = 80
+	// PREVIEW_DEPTH is how many directory levels the preview shows, like `tree -L 2`
+	PREVIEW_DEPTH = 2
+	// MAX_PREVIEW_LINES bounds the directory listing of huge repositories
+	MAX_PREVIEW_LINES = 200
+	// PREVIEW_COMMAND_TIMEOUT stops preview commands that would freeze the picker
+	PREVIEW_COMMAND_TIMEOUT = 2 * time.Second
+)
+
+// ESCAPE_SEQUENCE matches the colors and other terminal escape sequences preview commands may print (such as
+// `tree -C`), which would throw off the width of the preview pane
+var ESCAPE_SEQUENCE = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// listHeight is the number of items shown - everything but the prompt, status and help lines
+func listHeight(height int) int {
+	return max(1, height-3)
+}
+
+// render draws the whole screen: the prompt, the status line, the items next to the preview of the highlighted one,
+// and the help line. Every line is cut to the terminal's width, so nothing wraps.
+func (state *state) render(width int, height int) string {
+	listWidth, previewWidth := width, 0
+	if width >= MIN_PREVIEW_WIDTH {
+		listWidth = width / 2
+		previewWidth = width - listWidth - 2
+	}
+
+	rows := listHeight(height)
+	if state.cursor < state.offset {
+		state.offset = state.cursor
+	} else if state.cursor >= state.offset+rows {
+		state.offset = state.cursor - rows + 1
+	}
+
+	var preview []string
+	if previewWidth > 0 && len(state.items) > 0 {
+		preview = state.preview(state.items[state.cursor].Path)
+	}
+
+	var lines []string
+	lines = append(lines, terminal.BOLD_COLOR+"> "+terminal.RESET_COLORS+fit(string(state.query), width-2))
+	if state.err != nil {
+		lines = append(lines, terminal.RED_COLOR+fit("  "+state.err.Error(), width)+terminal.RESET_COLORS)
+	} else {
+		lines = append(lines, terminal.DIM_COLOR+fit(fmt.Sprintf("  %d matches", len(state.items)), width)+terminal.RESET_COLORS)
+	}
+
+	for row := 0; row < rows; row++ {
+		line := strings.Repeat(" ", listWidth)
+		if index := state.offset + row; index < len(state.items) {
+			item := state.items[index]
+			if index == state.cursor {
+				line = terminal.BOLD_COLOR + terminal.CYAN_COLOR + fit("> "+item.Name+"  "+item.Details, listWidth) + terminal.RESET_COLORS
+			} else {
+				line = fit("  "+item.Name+"  "+item.Details, listWidth)
+			}
+		}
+
+		if previewWidth > 0 {
+			line += terminal.DIM_COLOR + "│ " + terminal.RESET_COLORS
+			if row < len(preview) {
+				line += fit(preview[row], previewWidth)
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, terminal.DIM_COLOR+fit(state.helpLine(), width)+terminal.RESET_COLORS)
+
+	var screen strings.Builder
+	screen.WriteString(CURSOR_HOME)
+	for i, line := range lines {
+		if i > 0 {
+			screen.WriteString("\r\n")
+		}
+		screen.WriteString(line + CLEAR_LINE)
+	}
+	// Leave the cursor at the end of the query
+	fmt.Fprintf(&screen, "\033[1;%dH", min(width, len(state.query)+3))
+	return screen.String()
+}
+
+func (state *state) helpLine() string {
+	return fmt.Sprintf("enter: %s  ctrl-o: %s  ctrl-a: %s  ctrl-b: %s  esc: quit", ACTION_NAMES[state.enterAction],
+		ACTION_NAMES[ACTION_OPEN_IDE], ACTION_NAMES[ACTION_OPEN_ALTERNATIVE_IDE], ACTION_NAMES[ACTION_OPEN_WEB])
+}
+
+// fit cuts text to width characters, or pads it with spaces up to width
+func fit(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
+
+// preview returns the preview lines of path, built once per path
+func (state *state) preview(path string) []string {
+	if lines, found := state.previews[path]; found {
+		return lines
+	}
+	var lines []string
+	if state.previewCommand != "" {
+		lines = commandPreview(state.previewCommand, path)
+	} else {
+		lines = buildPreview(path)
+	}
+	state.previews[path] = lines
+	return lines
+}
+
+// buildPreview describes path: its branch and remotes when it is in a git repository, followed by its directory tree
+func buildPreview(path string) []string {
+	lines := []string{path}
+
+	if repoDir, found := repositoryOf(path); found {
+		if branch, err := repoindex.CurrentBranch(repoDir); err == nil {
+			lines = append(lines, "branch: "+branch)
+		}
+		if remotes, err := repoindex.ReadRemotes(repoDir); err == nil {
+			for _, remote := range remotes {
+				lines = append(lines, "remote: "+remote.Name+" "+remote.Url)
+			}
+		}
+	}
+
+	lines = append(lines, "")
+	return append(lines, directoryTree(path, PREVIEW_DEPTH, MAX_PREVIEW_LINES)...)
+}
+
+// commandPreview runs previewCommand with sh, replacing {} with path, and returns its output - errors included
+func commandPreview(previewCommand string, path string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), PREVIEW_COMMAND_TIMEOUT)
+	defer cancel()
+
+	quotedPath := "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+	output, err := exec.CommandContext(ctx, "sh", "-c", strings.ReplaceAll(previewCommand, "{}", quotedPath)).CombinedOutput()
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if len(lines) == MAX_PREVIEW_LINES {
+			break
+		}
+		lines = append(lines, strings.ReplaceAll(ESCAPE_SEQUENCE.ReplaceAllString(line, ""), "\t", "    "))
+	}
+	if err != nil {
+		lines = append(lines, "", fmt.Sprintf("preview failed: %v", err))
+	}
+	return lines
+}
+
+// repositoryOf returns the root of the git repository containing path
+func repositoryOf(path string) (string, bool) {
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		if dir == filepath.Dir(dir) {
+			return "", false
+		}
+	}
+}
+
+// directoryTree lists dir like `tree -L depth` does, up to limit lines. The .git directory is left out.
+func directoryTree(dir string, depth int, limit int) []string {
+	var lines []string
+
+	var walk func(dir string, indent string, depth int)
+	walk = func(dir string, indent string, depth int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+
+		var visible []os.DirEntry
+		for _, entry := range entries {
+			if entry.Name() != ".git" {
+				visible = append(visible, entry)
+			}
+		}
+
+		for i, entry := range visible {
+			if len(lines) >= limit {
+				return
+			}
+
+			branch, childIndent := "├── ", "│   "
+			if i == len(visible)-1 {
+				branch, childIndent = "└── ", "    "
+			}
+
+			name := entry.Name()
+			if entry.IsDir() {
+				name += "/"
+			}
+			lines = append(lines, indent+branch+name)
+
+			if entry.IsDir() && depth > 1 {
+				walk(filepath.Join(dir, entry.Name()), indent+childIndent, depth-1)
+			}
+		}
+	}
+
+	walk(dir, "", depth)
+	return lines
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package picker
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+var resizeSignals []os.Signal
+
+func makeRaw(fd int) (func(), error) {
+	return nil, fmt.Errorf("the interactive picker is not supported on %s", runtime.GOOS)
+}
+
+func terminalSize(fd int) (int, int, error) {
+	return 0, 0, fmt.Errorf("the interactive picker is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,56 @@
+//go:build linux || darwin
+
+package picker
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// resizeSignals are delivered when the terminal window is resized
+var resizeSignals = []os.Signal{syscall.SIGWINCH}
+
+// makeRaw switches the terminal to raw mode - unbuffered, unechoed input with no signal keys - like cfmakeraw(3).
+// The returned function restores the original mode.
+func makeRaw(fd int) (func(), error) {
+	var original syscall.Termios
+	if err := ioctl(fd, ioctlGetTermios, uintptr(unsafe.Pointer(&original))); err != nil {
+		return nil, err
+	}
+
+	raw := original
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := ioctl(fd, ioctlSetTermios, uintptr(unsafe.Pointer(&raw))); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		ioctl(fd, ioctlSetTermios, uintptr(unsafe.Pointer(&original)))
+	}, nil
+}
+
+// terminalSize returns the width and height of the terminal, in characters
+func terminalSize(fd int) (int, int, error) {
+	var size struct {
+		rows, columns, xPixels, yPixels uint16
+	}
+	if err := ioctl(fd, syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&size))); err != nil {
+		return 0, 0, err
+	}
+	return int(size.columns), int(size.rows), nil
+}
+
+func ioctl(fd int, request uintptr, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
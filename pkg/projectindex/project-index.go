@@ -1,26 +1,38 @@
 package projectindex
 
 import (
-	"errors"
-	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	config "ronkitay.com/griffin/pkg/configuration"
 	csvHelper "ronkitay.com/griffin/pkg/csv"
+	"ronkitay.com/griffin/pkg/language"
+	matcher "ronkitay.com/griffin/pkg/matcher"
 	repoIndex "ronkitay.com/griffin/pkg/repoindex"
 )
 
 type ProjectData struct {
 	BaseDir  string
 	FullName string
-	Type     string
+	// Type is the primary language of the project
+	Type string
+	// Languages are all the languages detected in the project, starting with Type
+	Languages []string
+}
+
+// INDEX_SCHEMA lists the columns of the project index, in the order of AsCsvRecord.
+// Version 2 indexes have no languages column, and version 3 adds it.
+var INDEX_SCHEMA = csvHelper.Schema{
+	Version:       3,
+	Columns:       []string{"baseDir", "name", "type", "languages"},
+	LegacyColumns: []string{"baseDir", "name", "type"},
 }
 
 func (datum ProjectData) AsCsvRecord() []string {
-	return []string{datum.BaseDir, datum.FullName, datum.Type}
+	return []string{datum.BaseDir, datum.FullName, datum.Type, strings.Join(datum.Languages, ",")}
 }
 
 func (datum ProjectData) ToString() string {
@@ -31,106 +43,165 @@ func (datum ProjectData) Matchable() []string {
 	return []string{datum.FullName, filepath.Join(datum.BaseDir, datum.FullName)}
 }
 
-func FromCsvRecord(data []string) (ProjectData, error) {
-	return ProjectData{
-		BaseDir:  data[0],
-		FullName: data[1],
-		Type:     data[2],
-	}, nil
+// Fields exposes the project's fields to structured queries (e.g. `lang:go`).
+func (datum ProjectData) Fields() map[string]matcher.Field {
+	return map[string]matcher.Field{
+		"name": {Values: []string{datum.FullName}},
+		"lang": {Values: datum.Languages, Exact: true},
+		"type": {Values: []string{datum.Type}, Exact: true},
+		"root": {Values: []string{datum.BaseDir}},
+		"path": {Values: []string{datum.ToString()}},
+	}
 }
 
-func LoadIndex() []ProjectData {
-	return csvHelper.LoadIndex[ProjectData](config.LoadConfiguration().ProjectListLocation, FromCsvRecord)
+func FromCsvRecord(record csvHelper.IndexRecord) (ProjectData, error) {
+	projectData := ProjectData{
+		BaseDir:  record.Get("baseDir"),
+		FullName: record.Get("name"),
+		Type:     record.Get("type"),
+	}
+	if languages := record.Get("languages"); languages != "" {
+		projectData.Languages = strings.Split(languages, ",")
+	} else if projectData.Type != "" {
+		projectData.Languages = []string{projectData.Type}
+	}
+	return projectData, nil
 }
 
-func BuildProjectIndex() {
+func LoadIndex(configuration config.Configuration) ([]ProjectData, error) {
+	return csvHelper.LoadIndex(configuration.ProjectListLocation, INDEX_SCHEMA, FromCsvRecord, configuration.Warn)
+}
 
-	repos := repoIndex.LoadIndex(true, true)
+func BuildProjectIndex(configuration config.Configuration) error {
+	detector, err := configuration.LanguageDetector()
+	if err != nil {
+		return err
+	}
+
+	repos, err := repoIndex.LoadIndex(configuration, true, true)
+	if err != nil {
+		return err
+	}
 
 	var projects []ProjectData
+	scannedRepos := make(map[string]struct{})
 
 	for _, repo := range repos {
 		repoRoot := filepath.Join(repo.BaseDir, repo.FullName)
+		// A worktree inside a root is indexed both as a repo and as a worktree - only scan it once
+		if _, scanned := scannedRepos[repoRoot]; scanned {
+			continue
+		}
+		scannedRepos[repoRoot] = struct{}{}
 
-		scanRepoForProjects(repoRoot, &projects)
+		scanRepoForProjects(repoRoot, detector, configuration.Warn, &projects)
 	}
 
-	csvHelper.SaveIndex(config.LoadConfiguration().ProjectListLocation, projects)
+	return csvHelper.SaveIndex(configuration.ProjectListLocation, INDEX_SCHEMA, projects)
 }
 
-func scanRepoForProjects(rootLocation string, projects *[]ProjectData) {
-	err := filepath.WalkDir(rootLocation, visitDirs(rootLocation, projects))
+// RefreshProjectIndex re-scans the changed repositories, as well as any repository that has no
+// projects in the index yet, and reuses the indexed projects of all other repositories.
+func RefreshProjectIndex(configuration config.Configuration, changedRepos map[string]struct{}) error {
+	projectListLocation := configuration.ProjectListLocation
+	if _, err := os.Stat(projectListLocation); err != nil {
+		return BuildProjectIndex(configuration)
+	}
 
+	detector, err := configuration.LanguageDetector()
 	if err != nil {
-		fmt.Printf("Error walking the path %v: %v\n", rootLocation, err)
+		return err
 	}
-}
 
-func visitDirs(rootLocation string, projects *[]ProjectData) fs.WalkDirFunc {
-	return func(path string, info os.DirEntry, err error) error {
-		if err != nil {
-			fmt.Println(err) // can't walk here,
-			return nil       // but continue walking elsewhere
-		}
+	repos, err := repoIndex.LoadIndex(configuration, true, true)
+	if err != nil {
+		return err
+	}
 
-		if info.IsDir() {
-			if dirCanBeSkipped(path) {
-				return filepath.SkipDir
-			}
+	repoRoots := make(map[string]struct{})
+	for _, repo := range repos {
+		repoRoots[filepath.Join(repo.BaseDir, repo.FullName)] = struct{}{}
+	}
 
-			language, error := matchedProgrammingLanguage(path)
-			if error == nil {
-				dir, name := dirAndName(rootLocation, path)
-				projectData := ProjectData{BaseDir: dir, FullName: name, Type: language}
-				*projects = append(*projects, projectData)
-			}
+	indexedProjectList, err := LoadIndex(configuration)
+	if err != nil {
+		return err
+	}
+
+	indexedProjects := make(map[string][]ProjectData)
+	for _, project := range indexedProjectList {
+		if repoRoot, found := owningRepo(project.ToString(), repoRoots); found {
+			indexedProjects[repoRoot] = append(indexedProjects[repoRoot], project)
 		}
+	}
 
-		return nil
+	var projects []ProjectData
+	scannedRepos := make(map[string]struct{})
+
+	for _, repo := range repos {
+		repoRoot := filepath.Join(repo.BaseDir, repo.FullName)
+		if _, scanned := scannedRepos[repoRoot]; scanned {
+			continue
+		}
+		scannedRepos[repoRoot] = struct{}{}
+
+		_, changed := changedRepos[repoRoot]
+		repoProjects, indexed := indexedProjects[repoRoot]
+		if changed || !indexed {
+			scanRepoForProjects(repoRoot, detector, configuration.Warn, &projects)
+		} else {
+			projects = append(projects, repoProjects...)
+		}
 	}
-}
 
-var skipIndicators = []string{".git", ".terraform", "node_modules", ".venv", "venv", "target", "build"}
+	return csvHelper.SaveIndex(projectListLocation, INDEX_SCHEMA, projects)
+}
 
-func dirCanBeSkipped(path string) bool {
-	for _, dirName := range skipIndicators {
-		if filepath.Base(path) == dirName {
-			return true
+func owningRepo(path string, repoRoots map[string]struct{}) (string, bool) {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, found := repoRoots[dir]; found {
+			return dir, true
+		}
+		if dir == filepath.Dir(dir) {
+			return "", false
 		}
 	}
-	return false
 }
 
-var fileToLanguageMapping = map[string]string{
-	"build.gradle":     "java",
-	"build.gradle.kts": "kotlin",
-	"pom.xml":          "java",
-	"go.mod":           "go",
-	"Pipfile":          "python",
-	"requirements.txt": "python",
-	"poetry.toml": 		"python",
-	"pyproject.toml":   "python",
-	"Cargo.toml":       "rust",
-	"package.json":     "node",
+// scanRepoForProjects adds the projects under rootLocation, reporting the paths it cannot walk with warn
+func scanRepoForProjects(rootLocation string, detector *language.Detector, warn func(format string, args ...any), projects *[]ProjectData) {
+	err := filepath.WalkDir(rootLocation, visitDirs(rootLocation, detector, warn, projects))
+
+	if err != nil {
+		warn("Error walking the path %v: %v", rootLocation, err)
+	}
 }
 
-func matchedProgrammingLanguage(path string) (string, error) {
+func visitDirs(rootLocation string, detector *language.Detector, warn func(format string, args ...any), projects *[]ProjectData) fs.WalkDirFunc {
+	return func(path string, info os.DirEntry, err error) error {
+		if err != nil {
+			warn("%v", err) // can't walk here,
+			return nil      // but continue walking elsewhere
+		}
+
+		if info.IsDir() {
+			if DirCanBeSkipped(path) {
+				return filepath.SkipDir
+			}
 
-	for file, language := range fileToLanguageMapping {
-		indicatorFile := filepath.Join(path, file)
-		_, err := os.Stat(indicatorFile)
-		if err == nil {
-			return language, nil
+			if languages := detector.DetectAll(path); len(languages) > 0 {
+				dir, name := dirAndName(rootLocation, path)
+				projectData := ProjectData{BaseDir: dir, FullName: name, Type: languages[0], Languages: languages}
+				*projects = append(*projects, projectData)
+			}
 		}
-	}
 
-	indicatorFile := filepath.Join(path, "Makefile")
-	_, err := os.Stat(indicatorFile)
-	if err == nil {
-		return "Any", nil
+		return nil
 	}
+}
 
-	return "", errors.New("No language matched")
+func DirCanBeSkipped(path string) bool {
+	return slices.Contains(language.SKIPPED_DIRS, filepath.Base(path))
 }
 
 func dirAndName(rootLocation string, path string) (string, string) {
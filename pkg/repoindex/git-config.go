@@ -0,0 +1,255 @@
+package repoindex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type gitConfigEntry struct {
+	section    string
+	subsection string
+	key        string
+	value      string
+}
+
+// readGitRemotes reads the remotes of the repository at repoDir straight from its git config,
+// following the `gitdir:` pointer files used by worktrees and submodules.
+// Remotes are returned in the order they are declared, with `url.<base>.insteadOf` rewrites applied.
+func readGitRemotes(repoDir string) ([]Remote, error) {
+	gitDir, err := resolveGitDir(repoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := os.Open(filepath.Join(commonGitDir(gitDir), "config"))
+	if err != nil {
+		return nil, err
+	}
+	defer configFile.Close()
+
+	entries, err := parseGitConfig(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse git config of %s: %v", repoDir, err)
+	}
+
+	var remotes []Remote
+	rewrites := map[string]string{}
+	for _, entry := range entries {
+		switch {
+		case entry.section == "remote" && entry.key == "url":
+			if !hasRemote(remotes, entry.subsection) {
+				remotes = append(remotes, Remote{Name: entry.subsection, Url: entry.value})
+			}
+		case entry.section == "url" && entry.key == "insteadof":
+			rewrites[entry.value] = entry.subsection
+		}
+	}
+
+	for i := range remotes {
+		remotes[i].Url = applyInsteadOf(remotes[i].Url, rewrites)
+	}
+
+	return remotes, nil
+}
+
+// ReadRemotes returns the remotes of the repository at repoDir, read from its git config without running git.
+func ReadRemotes(repoDir string) ([]Remote, error) {
+	return readGitRemotes(repoDir)
+}
+
+// CurrentBranch returns the branch checked out in the repository at repoDir,
+// or the abbreviated commit when HEAD is detached.
+func CurrentBranch(repoDir string) (string, error) {
+	gitDir, err := resolveGitDir(repoDir)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+
+	head := strings.TrimSpace(string(content))
+	if ref, isRef := strings.CutPrefix(head, "ref:"); isRef {
+		return strings.TrimPrefix(strings.TrimSpace(ref), "refs/heads/"), nil
+	}
+	if len(head) > 7 {
+		head = head[:7]
+	}
+	return head, nil
+}
+
+func hasRemote(remotes []Remote, name string) bool {
+	for _, remote := range remotes {
+		if remote.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyInsteadOf rewrites url using the longest matching `insteadOf` prefix, like git does.
+func applyInsteadOf(url string, rewrites map[string]string) string {
+	longestMatch := ""
+	for prefix := range rewrites {
+		if strings.HasPrefix(url, prefix) && len(prefix) > len(longestMatch) {
+			longestMatch = prefix
+		}
+	}
+	if longestMatch == "" {
+		return url
+	}
+	return rewrites[longestMatch] + strings.TrimPrefix(url, longestMatch)
+}
+
+// resolveGitDir returns the git directory of a repository - either its `.git` directory
+// or the location a `.git` file points to.
+func resolveGitDir(repoDir string) (string, error) {
+	gitPath := filepath.Join(repoDir, ".git")
+	info, err := os.Stat(gitPath)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return gitPath, nil
+	}
+
+	content, err := os.ReadFile(gitPath)
+	if err != nil {
+		return "", err
+	}
+
+	line := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("invalid gitdir file: %s", gitPath)
+	}
+
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repoDir, gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
+
+// commonGitDir returns the directory holding the shared config of gitDir.
+// Linked worktrees keep a `commondir` file pointing back to the main repository's git directory.
+func commonGitDir(gitDir string) string {
+	content, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+
+	commonDir := strings.TrimSpace(string(content))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	return filepath.Clean(commonDir)
+}
+
+// parseGitConfig parses the subset of the git config syntax needed to read remotes:
+// sections with optional subsections, comments, quoted values and line continuations.
+// Section and key names are lower-cased, subsection names are kept as-is.
+func parseGitConfig(reader io.Reader) ([]gitConfigEntry, error) {
+	var entries []gitConfigEntry
+	var section, subsection string
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		for strings.HasSuffix(line, "\\") && scanner.Scan() {
+			lineNumber++
+			line = strings.TrimSuffix(line, "\\") + scanner.Text()
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.LastIndex(line, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNumber)
+			}
+			section, subsection = parseSectionHeader(line[1:end])
+			continue
+		}
+
+		if section == "" {
+			return nil, fmt.Errorf("line %d: key outside of a section", lineNumber)
+		}
+
+		key, value, hasValue := strings.Cut(line, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !hasValue {
+			value = "true"
+		}
+		entries = append(entries, gitConfigEntry{section: section, subsection: subsection, key: key, value: parseConfigValue(value)})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func parseSectionHeader(header string) (string, string) {
+	header = strings.TrimSpace(header)
+	if name, rest, found := strings.Cut(header, " "); found {
+		subsection := strings.TrimSpace(rest)
+		subsection = strings.TrimSuffix(strings.TrimPrefix(subsection, "\""), "\"")
+		subsection = strings.ReplaceAll(subsection, "\\\"", "\"")
+		subsection = strings.ReplaceAll(subsection, "\\\\", "\\")
+		return strings.ToLower(name), subsection
+	}
+	// Deprecated [section.subsection] syntax
+	if name, subsection, found := strings.Cut(header, "."); found {
+		return strings.ToLower(name), strings.ToLower(subsection)
+	}
+	return strings.ToLower(header), ""
+}
+
+func parseConfigValue(raw string) string {
+	var value strings.Builder
+	inQuotes := false
+	pendingSpace := ""
+
+	raw = strings.TrimSpace(raw)
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == '\\' && i+1 < len(raw):
+			i++
+			value.WriteString(pendingSpace)
+			pendingSpace = ""
+			switch raw[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case 't':
+				value.WriteByte('\t')
+			default:
+				value.WriteByte(raw[i])
+			}
+			continue
+		case (c == '#' || c == ';') && !inQuotes:
+			return value.String()
+		case (c == ' ' || c == '\t') && !inQuotes:
+			pendingSpace += string(c)
+			continue
+		default:
+			value.WriteString(pendingSpace)
+			pendingSpace = ""
+			value.WriteByte(c)
+		}
+	}
+	return value.String()
+}
@@ -0,0 +1,102 @@
+package repoindex
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGitConfig(t *testing.T) {
+	config := `
+# a comment
+[core]
+	bare = false
+[remote "origin"]
+	url = git@github.com:user/repo.git ; trailing comment
+	fetch = +refs/heads/*:refs/remotes/origin/*
+[Remote "Upstream"]
+	URL = "https://gitlab.com/group/my repo.git"
+[url "https://github.com/"]
+	insteadOf = gh:
+[branch.main]
+	merge
+`
+	entries, err := parseGitConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("parseGitConfig failed: %v", err)
+	}
+
+	expected := []gitConfigEntry{
+		{section: "core", key: "bare", value: "false"},
+		{section: "remote", subsection: "origin", key: "url", value: "git@github.com:user/repo.git"},
+		{section: "remote", subsection: "origin", key: "fetch", value: "+refs/heads/*:refs/remotes/origin/*"},
+		{section: "remote", subsection: "Upstream", key: "url", value: "https://gitlab.com/group/my repo.git"},
+		{section: "url", subsection: "https://github.com/", key: "insteadof", value: "gh:"},
+		{section: "branch", subsection: "main", key: "merge", value: "true"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Unexpected entries.\nExpected: %v\nGot:      %v", expected, entries)
+	}
+}
+
+func TestReadGitRemotes(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoDir := filepath.Join(tmpDir, "repo")
+	os.MkdirAll(repoDir, 0755)
+
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.email", "test@test.com")
+	runGit(t, repoDir, "config", "user.name", "Test")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "init")
+	runGit(t, repoDir, "remote", "add", "origin", "git@github.com:user/repo.git")
+	runGit(t, repoDir, "remote", "add", "upstream", "gh:acme/repo.git")
+	runGit(t, repoDir, "config", "url.https://github.com/.insteadOf", "gh:")
+
+	wt := filepath.Join(tmpDir, "wt")
+	runGit(t, repoDir, "worktree", "add", "--detach", wt, "master")
+
+	expected := []Remote{
+		{Name: "origin", Url: "git@github.com:user/repo.git"},
+		{Name: "upstream", Url: "https://github.com/acme/repo.git"},
+	}
+
+	for _, dir := range []string{repoDir, wt} {
+		remotes, err := readGitRemotes(dir)
+		if err != nil {
+			t.Fatalf("readGitRemotes(%s) failed: %v", dir, err)
+		}
+		if !reflect.DeepEqual(remotes, expected) {
+			t.Errorf("Unexpected remotes for %s.\nExpected: %v\nGot:      %v", dir, expected, remotes)
+		}
+	}
+}
+
+func TestReadGitRemotes_NotARepo(t *testing.T) {
+	if _, err := readGitRemotes(t.TempDir()); err == nil {
+		t.Errorf("Expected an error for a directory without .git")
+	}
+}
+
+func TestCurrentBranch(t *testing.T) {
+	tmpDir := t.TempDir()
+	repoDir := filepath.Join(tmpDir, "repo")
+	os.MkdirAll(repoDir, 0755)
+
+	runGit(t, repoDir, "init")
+	runGit(t, repoDir, "config", "user.email", "test@test.com")
+	runGit(t, repoDir, "config", "user.name", "Test")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "init")
+	runGit(t, repoDir, "checkout", "-b", "feature/picker")
+
+	wt := filepath.Join(tmpDir, "wt")
+	runGit(t, repoDir, "worktree", "add", "--detach", wt, "feature/picker")
+
+	if branch, err := CurrentBranch(repoDir); err != nil || branch != "feature/picker" {
+		t.Errorf("Expected feature/picker, got %q (%v)", branch, err)
+	}
+	if commit, err := CurrentBranch(wt); err != nil || len(commit) != 7 {
+		t.Errorf("Expected an abbreviated commit for a detached worktree, got %q (%v)", commit, err)
+	}
+}
@@ -0,0 +1,244 @@
+package repoindex
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const indexStateVersion = 1
+
+// indexState is what an incremental build remembers between runs: the contents of every directory
+// walked (keyed by path and valid while the directory's mtime is unchanged) and the git metadata of
+// every repository (valid while its config and worktrees directory are unchanged).
+type indexState struct {
+	Version int                  `json:"version"`
+	Dirs    map[string]dirState  `json:"dirs"`
+	Repos   map[string]repoState `json:"repos"`
+
+	lock      sync.Mutex
+	seenDirs  map[string]struct{}
+	seenRepos map[string]struct{}
+	// warn reports directories and repositories that cannot be read
+	warn func(format string, args ...any)
+}
+
+type dirState struct {
+	ModTime  int64        `json:"modTime"`
+	IsRepo   bool         `json:"isRepo,omitempty"`
+	Children []childEntry `json:"children,omitempty"`
+}
+
+// childEntry is a sub-directory or an archive (*.git file) of a walked directory.
+type childEntry struct {
+	Name  string `json:"name"`
+	IsDir bool   `json:"isDir,omitempty"`
+}
+
+type repoState struct {
+	ConfigModTime    int64    `json:"configModTime"`
+	Remotes          []Remote `json:"remotes,omitempty"`
+	WorktreesModTime int64    `json:"worktreesModTime"`
+	Worktrees        []string `json:"worktrees,omitempty"`
+	HasWorktrees     bool     `json:"hasWorktrees,omitempty"`
+}
+
+func newIndexState() *indexState {
+	return &indexState{
+		Version:   indexStateVersion,
+		Dirs:      map[string]dirState{},
+		Repos:     map[string]repoState{},
+		seenDirs:  map[string]struct{}{},
+		seenRepos: map[string]struct{}{},
+		warn:      func(string, ...any) {},
+	}
+}
+
+// loadIndexState reads the state saved by a previous build, starting from scratch if there is none.
+func loadIndexState(filePath string, warn func(format string, args ...any)) *indexState {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return newIndexState()
+	}
+
+	state := newIndexState()
+	if err := json.Unmarshal(data, state); err != nil || state.Version != indexStateVersion {
+		warn("Ignoring unreadable repo index state: %s", filePath)
+		return newIndexState()
+	}
+	if state.Dirs == nil {
+		state.Dirs = map[string]dirState{}
+	}
+	if state.Repos == nil {
+		state.Repos = map[string]repoState{}
+	}
+	return state
+}
+
+// save writes the state, dropping every directory and repository not seen during this build.
+func (state *indexState) save(filePath string) error {
+	for path := range state.Dirs {
+		if _, seen := state.seenDirs[path]; !seen {
+			delete(state.Dirs, path)
+		}
+	}
+	for path := range state.Repos {
+		if _, seen := state.seenRepos[path]; !seen {
+			delete(state.Repos, path)
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filePath+".new", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filePath+".new", filePath)
+}
+
+// walk collects the candidates under rootLocation in lexical order (like filepath.Walk),
+// only reading the directories whose mtime changed since the previous build.
+func (state *indexState) walk(rootLocation string) []candidate {
+	var candidates []candidate
+	state.walkDir(rootLocation, rootLocation, &candidates)
+	return candidates
+}
+
+func (state *indexState) walkDir(rootLocation string, path string, candidates *[]candidate) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		state.warn("%v", err) // can't walk here, but continue walking elsewhere
+		return
+	}
+
+	if !info.IsDir() {
+		if strings.HasSuffix(path, ".git") {
+			*candidates = append(*candidates, candidate{rootLocation: rootLocation, path: path, isArchive: true})
+		}
+		return
+	}
+
+	modTime := info.ModTime().UnixNano()
+	state.lock.Lock()
+	dir, cached := state.Dirs[path]
+	state.lock.Unlock()
+
+	if !cached || dir.ModTime != modTime {
+		dir, err = readDirState(path, modTime)
+		if err != nil {
+			state.warn("%v", err)
+			return
+		}
+	}
+
+	state.lock.Lock()
+	state.Dirs[path] = dir
+	state.seenDirs[path] = struct{}{}
+	state.lock.Unlock()
+
+	if dir.IsRepo {
+		*candidates = append(*candidates, candidate{rootLocation: rootLocation, path: path})
+		return
+	}
+
+	for _, child := range dir.Children {
+		state.walkDir(rootLocation, filepath.Join(path, child.Name), candidates)
+	}
+}
+
+func readDirState(path string, modTime int64) (dirState, error) {
+	// Check if the directory contains a .git directory
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		return dirState{ModTime: modTime, IsRepo: true}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return dirState{}, err
+	}
+
+	var children []childEntry
+	for _, entry := range entries {
+		if entry.IsDir() {
+			children = append(children, childEntry{Name: entry.Name(), IsDir: true})
+		} else if strings.HasSuffix(entry.Name(), ".git") {
+			children = append(children, childEntry{Name: entry.Name()})
+		}
+	}
+	return dirState{ModTime: modTime, Children: children}, nil
+}
+
+// remotes returns the remotes of the repository at path, re-reading them only when its config changed.
+func (state *indexState) remotes(path string) ([]Remote, error) {
+	configModTime := gitFileModTime(path, "config")
+
+	state.lock.Lock()
+	repo, cached := state.Repos[path]
+	state.seenRepos[path] = struct{}{}
+	state.lock.Unlock()
+
+	if cached && configModTime != 0 && repo.ConfigModTime == configModTime {
+		return repo.Remotes, nil
+	}
+
+	remotes, err := getGitRemotes(path)
+	if err != nil {
+		return nil, err
+	}
+
+	state.lock.Lock()
+	repo = state.Repos[path]
+	repo.ConfigModTime = configModTime
+	repo.Remotes = remotes
+	state.Repos[path] = repo
+	state.lock.Unlock()
+
+	return remotes, nil
+}
+
+// worktrees returns the worktrees of the repository at path, re-listing them only when they changed.
+func (state *indexState) worktrees(path string) []string {
+	worktreesModTime := gitFileModTime(path, "worktrees")
+
+	state.lock.Lock()
+	repo, cached := state.Repos[path]
+	state.lock.Unlock()
+
+	if cached && repo.HasWorktrees && repo.WorktreesModTime == worktreesModTime {
+		return repo.Worktrees
+	}
+
+	worktrees, err := getWorktrees(path)
+	if err != nil {
+		return nil
+	}
+
+	state.lock.Lock()
+	repo = state.Repos[path]
+	repo.WorktreesModTime = worktreesModTime
+	repo.Worktrees = worktrees
+	repo.HasWorktrees = true
+	state.Repos[path] = repo
+	state.lock.Unlock()
+
+	return worktrees
+}
+
+// gitFileModTime returns the mtime of a file in the shared git directory of the repository at
+// repoDir, or 0 if it does not exist.
+func gitFileModTime(repoDir string, name string) int64 {
+	gitDir, err := resolveGitDir(repoDir)
+	if err != nil {
+		return 0
+	}
+	info, err := os.Stat(filepath.Join(commonGitDir(gitDir), name))
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
@@ -0,0 +1,68 @@
+package repoindex
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLocateRepos_Incremental(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "root")
+	stateFile := filepath.Join(tmpDir, "repo.state.json")
+
+	for _, name := range []string{"group/kept", "group/removed"} {
+		repoDir := filepath.Join(root, name)
+		os.MkdirAll(repoDir, 0755)
+		runGit(t, repoDir, "init")
+		runGit(t, repoDir, "remote", "add", "origin", "git@github.com:user/"+filepath.Base(name)+".git")
+	}
+
+	state := newIndexState()
+	initial := locateRepos([]string{root}, 2, state)
+	if err := state.save(stateFile); err != nil {
+		t.Fatalf("Failed saving state: %v", err)
+	}
+	if len(initial) != 4 {
+		t.Fatalf("Expected 4 entries, got %d: %v", len(initial), initial)
+	}
+
+	// Make sure the modified directories get a different mtime
+	time.Sleep(10 * time.Millisecond)
+
+	os.RemoveAll(filepath.Join(root, "group", "removed"))
+	addedDir := filepath.Join(root, "group", "added")
+	os.MkdirAll(addedDir, 0755)
+	runGit(t, addedDir, "init")
+	runGit(t, addedDir, "remote", "add", "origin", "git@gitlab.com:user/added.git")
+	runGit(t, filepath.Join(root, "group", "kept"), "remote", "set-url", "origin", "git@gitlab.com:user/moved.git")
+
+	state = loadIndexState(stateFile, t.Logf)
+	if _, cached := state.Dirs[filepath.Join(root, "group")]; !cached {
+		t.Fatalf("Expected the state to be loaded from %s", stateFile)
+	}
+	updated := locateRepos([]string{root}, 2, state)
+
+	reposByName := map[string]RepoData{}
+	for _, r := range updated {
+		reposByName[r.FullName] = r
+	}
+
+	if _, found := reposByName["group/removed"]; found {
+		t.Errorf("Expected removed repo to be pruned: %v", updated)
+	}
+	if added, found := reposByName["group/added"]; !found || added.Url != "https://gitlab.com/user/added" {
+		t.Errorf("Expected added repo to be indexed: %v", updated)
+	}
+	if kept := reposByName["group/kept"]; kept.Url != "https://gitlab.com/user/moved" {
+		t.Errorf("Expected changed remote to be re-read, got %v", kept)
+	}
+
+	if err := state.save(stateFile); err != nil {
+		t.Fatalf("Failed saving state: %v", err)
+	}
+	if _, found := loadIndexState(stateFile, t.Logf).Repos[filepath.Join(root, "group", "removed")]; found {
+		t.Errorf("Expected removed repo to be dropped from the state")
+	}
+}
@@ -2,6 +2,7 @@ package repoindex
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -12,18 +13,33 @@ import (
 
 	config "ronkitay.com/griffin/pkg/configuration"
 	csvHelper "ronkitay.com/griffin/pkg/csv"
+	matcher "ronkitay.com/griffin/pkg/matcher"
 )
 
+type Remote struct {
+	Name string
+	Url  string
+}
+
 type RepoData struct {
 	BaseDir  string
 	FullName string
 	Url      string
 	Type     string
 	Alias    string
+	Remotes  []Remote
+}
+
+// INDEX_SCHEMA lists the columns of the repository index, in the order of AsCsvRecord.
+// Unversioned indexes have the same columns, the older ones without alias and remotes.
+var INDEX_SCHEMA = csvHelper.Schema{
+	Version:       2,
+	Columns:       []string{"baseDir", "name", "url", "type", "alias", "remotes"},
+	LegacyColumns: []string{"baseDir", "name", "url", "type", "alias", "remotes"},
 }
 
 func (datum RepoData) AsCsvRecord() []string {
-	return []string{datum.BaseDir, datum.FullName, datum.Url, datum.Type, datum.Alias}
+	return []string{datum.BaseDir, datum.FullName, datum.Url, datum.Type, datum.Alias, remotesAsCsvField(datum.Remotes)}
 }
 
 func (datum RepoData) ToString() string {
@@ -37,16 +53,71 @@ func (datum RepoData) Matchable() []string {
 	return []string{datum.FullName}
 }
 
-func converter(noArchives bool, noDirs bool) func(csvData []string) (RepoData, error) {
-	return func(csvData []string) (RepoData, error) {
-		repoName := csvData[1]
-		locationType := csvData[3]
-		url := csvData[2]
-		parentDir := csvData[0]
-		alias := ""
-		if len(csvData) > 4 {
-			alias = csvData[4]
+// MatchableWithRemotes extends Matchable with the name and URL of every remote.
+func (datum RepoData) MatchableWithRemotes() []string {
+	matchable := datum.Matchable()
+	for _, remote := range datum.Remotes {
+		matchable = append(matchable, remote.Name, remote.Url)
+	}
+	return matchable
+}
+
+// Fields exposes the repository's fields to structured queries (e.g. `type:gitlab`).
+func (datum RepoData) Fields() map[string]matcher.Field {
+	var remotes []string
+	for _, remote := range datum.Remotes {
+		remotes = append(remotes, remote.Name, remote.Url)
+	}
+
+	return map[string]matcher.Field{
+		"name":   {Values: datum.Matchable()},
+		"alias":  {Values: []string{datum.Alias}},
+		"type":   {Values: []string{datum.Type}, Exact: true},
+		"root":   {Values: []string{datum.BaseDir}},
+		"path":   {Values: []string{datum.ToString()}},
+		"url":    {Values: []string{datum.Url}},
+		"remote": {Values: remotes},
+	}
+}
+
+// remotesAsCsvField encodes remotes as a JSON list, so names and URLs may contain any character.
+func remotesAsCsvField(remotes []Remote) string {
+	if len(remotes) == 0 {
+		return ""
+	}
+	field, err := json.Marshal(remotes)
+	if err != nil {
+		return ""
+	}
+	return string(field)
+}
+
+// remotesFromCsvField decodes remotesAsCsvField, as well as the comma separated name=url pairs of older indexes.
+func remotesFromCsvField(field string) []Remote {
+	var remotes []Remote
+	if strings.HasPrefix(field, "[") {
+		if err := json.Unmarshal([]byte(field), &remotes); err != nil {
+			return nil
 		}
+		return remotes
+	}
+
+	for _, pair := range strings.Split(field, ",") {
+		if name, url, found := strings.Cut(pair, "="); found {
+			remotes = append(remotes, Remote{Name: name, Url: url})
+		}
+	}
+	return remotes
+}
+
+func converter(noArchives bool, noDirs bool) csvHelper.ConverterFunc[RepoData] {
+	return func(record csvHelper.IndexRecord) (RepoData, error) {
+		repoName := record.Get("name")
+		locationType := record.Get("type")
+		url := record.Get("url")
+		parentDir := record.Get("baseDir")
+		alias := record.Get("alias")
+		remotes := remotesFromCsvField(record.Get("remotes"))
 
 		switch locationType {
 		case "dir":
@@ -57,10 +128,14 @@ func converter(noArchives bool, noDirs bool) func(csvData []string) (RepoData, e
 			if !noArchives {
 				return RepoData{BaseDir: parentDir, FullName: repoName, Url: url, Type: locationType, Alias: alias}, nil
 			}
+		case "local":
+			fallthrough
+		case "unknown":
+			fallthrough
 		case "gitlab":
 			fallthrough
 		case "github":
-			return RepoData{BaseDir: parentDir, FullName: repoName, Url: url, Type: locationType, Alias: alias}, nil
+			return RepoData{BaseDir: parentDir, FullName: repoName, Url: url, Type: locationType, Alias: alias, Remotes: remotes}, nil
 		}
 
 		return RepoData{}, errors.New("Path skipped or not supported")
@@ -68,131 +143,187 @@ func converter(noArchives bool, noDirs bool) func(csvData []string) (RepoData, e
 
 }
 
-func LoadIndex(noArchives bool, noDirs bool) []RepoData {
-	return csvHelper.LoadIndex[RepoData](config.LoadConfiguration().RepoListLocation, converter(noArchives, noDirs))
+func LoadIndex(configuration config.Configuration, noArchives bool, noDirs bool) ([]RepoData, error) {
+	return csvHelper.LoadIndex(configuration.RepoListLocation, INDEX_SCHEMA, converter(noArchives, noDirs), configuration.Warn)
 }
 
-func BuildRepoIndex() error {
-	configuration := config.LoadConfiguration()
-	configManager, err := config.NewConfigurationManager()
+// BuildRepoIndex builds the repository index. When incremental is set, only the directories and
+// repositories that changed since the previous build are re-scanned.
+func BuildRepoIndex(configuration config.Configuration, jobs int, incremental bool) error {
+	roots, err := configuration.RepoRoots()
 	if err != nil {
-		return fmt.Errorf("error initializing configuration: %v", err)
+		return fmt.Errorf("error getting repository roots: %v", err)
 	}
 
-	roots, err := configManager.GetRepoRoots()
-	if err != nil {
-		return fmt.Errorf("error getting repository roots: %v", err)
+	if err := os.MkdirAll(filepath.Dir(configuration.RepoListLocation), 0755); err != nil {
+		return fmt.Errorf("error creating index directory: %v", err)
 	}
 
-	processedRemotes := make(map[string]struct{})
-	var repos []RepoData
-	for _, rootLocation := range roots {
-		reposFromRoot := locateRepos(rootLocation, processedRemotes)
-		repos = append(repos, reposFromRoot...)
+	state := newIndexState()
+	if incremental {
+		state = loadIndexState(configuration.RepoIndexStateLocation, configuration.Warn)
 	}
+	state.warn = configuration.Warn
+
+	repos := locateRepos(roots, jobs, state)
 
-	if err := csvHelper.SaveIndex(configuration.RepoListLocation, repos); err != nil {
+	if err := csvHelper.SaveIndex(configuration.RepoListLocation, INDEX_SCHEMA, repos); err != nil {
 		return fmt.Errorf("error saving repo index: %v", err)
 	}
 
+	if err := state.save(configuration.RepoIndexStateLocation); err != nil {
+		return fmt.Errorf("error saving repo index state: %v", err)
+	}
+
 	return nil
 }
 
-func locateRepos(rootLocation string, processedRemotes map[string]struct{}) []RepoData {
-	var repos []RepoData
+// candidate is a location found while walking a root that may end up in the index.
+type candidate struct {
+	rootLocation string
+	path         string
+	isArchive    bool
+}
 
-	err := filepath.Walk(rootLocation, visit(rootLocation, &repos, processedRemotes))
-	if err != nil {
-		fmt.Printf("Error walking the path %v: %v\n", rootLocation, err)
+// resolvedRepo holds the git metadata of a candidate repository.
+type resolvedRepo struct {
+	remotes   []Remote
+	repoKey   string
+	err       error
+	worktrees []string
+}
+
+// locateRepos walks all roots concurrently and resolves the git metadata of the
+// discovered repositories using a pool of jobs workers, reusing whatever state
+// still holds from a previous build.
+// The result is ordered by root (in configuration order) and then by walk order,
+// so the same tree always produces the same index.
+func locateRepos(roots []string, jobs int, state *indexState) []RepoData {
+	candidatesPerRoot := parallelMap(jobs, roots, state.walk)
+
+	var candidates []candidate
+	for _, rootCandidates := range candidatesPerRoot {
+		candidates = append(candidates, rootCandidates...)
 	}
 
-	repos = deDuplicate(repos)
+	resolved := parallelMap(jobs, candidates, func(c candidate) resolvedRepo {
+		if c.isArchive {
+			return resolvedRepo{}
+		}
+		remotes, err := state.remotes(c.path)
+		return resolvedRepo{remotes: remotes, repoKey: repoKey(c.path, remotes), err: err}
+	})
+
+	// Worktrees are listed once per repository, by the first checkout (in index order) that shares it
+	processedRepos := make(map[string]struct{})
+	var worktreeOwners []int
+	for i, c := range candidates {
+		if c.isArchive || resolved[i].err != nil {
+			continue
+		}
+		if _, ok := processedRepos[resolved[i].repoKey]; !ok {
+			processedRepos[resolved[i].repoKey] = struct{}{}
+			worktreeOwners = append(worktreeOwners, i)
+		}
+	}
 
-	return repos
+	worktrees := parallelMap(jobs, worktreeOwners, func(i int) []string {
+		return state.worktrees(candidates[i].path)
+	})
+	for n, i := range worktreeOwners {
+		resolved[i].worktrees = worktrees[n]
+	}
+
+	var repos []RepoData
+	for i, c := range candidates {
+		if c.isArchive {
+			if repoData, ok := archiveRepoData(c.rootLocation, c.path); ok {
+				repos = append(repos, repoData)
+			}
+		} else if resolved[i].err != nil {
+			state.warn("Error: %v", resolved[i].err)
+		} else {
+			repos = appendRepo(repos, c.rootLocation, c.path, resolved[i])
+		}
+	}
+
+	return pruneMissing(deDuplicate(repos))
 }
 
-func visit(rootLocation string, paths *[]RepoData, processedRemotes map[string]struct{}) filepath.WalkFunc {
-	return func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			fmt.Println(err) // can't walk here,
-			return nil       // but continue walking elsewhere
+// pruneMissing drops entries whose path no longer exists, such as worktrees that were deleted
+// without being pruned from git.
+func pruneMissing(repos []RepoData) []RepoData {
+	var result []RepoData
+	for _, repo := range repos {
+		if _, err := os.Lstat(repo.ToString()); err == nil {
+			result = append(result, repo)
 		}
+	}
+	return result
+}
 
-		if info.IsDir() {
-			// Check if the directory contains a .git directory
-			gitPath := filepath.Join(path, ".git")
-			_, err := os.Stat(gitPath)
-			if err == nil {
-				repoDir, repoName := dirAndName(rootLocation, path)
-				remoteURL, err := getGitRemote(path)
-				if err != nil {
-					fmt.Println("Error:", err)
-				} else {
-					gitHttpUrl := gitURLToHTTP(remoteURL)
-					repoType := repoType(gitHttpUrl)
-					repoData := RepoData{BaseDir: repoDir, FullName: repoName, Url: gitHttpUrl, Type: repoType}
-					*paths = append(*paths, repoData)
-
-					if _, ok := processedRemotes[remoteURL]; !ok {
-						processedRemotes[remoteURL] = struct{}{}
-						worktrees, err := getWorktrees(path)
-						if err == nil {
-							for _, wtPath := range worktrees {
-								if wtPath == path {
-									continue
-								}
-
-								// Check if inside rootLocation
-								rel, err := filepath.Rel(rootLocation, wtPath)
-								if err == nil && !strings.HasPrefix(rel, "..") {
-									// Inside
-									wtDir, wtName := dirAndName(rootLocation, wtPath)
-									*paths = append(*paths, RepoData{BaseDir: wtDir, FullName: wtName, Url: gitHttpUrl, Type: repoType, Alias: repoName})
-								} else {
-									// Outside - use actual directory name with repo name as alias
-									*paths = append(*paths, RepoData{BaseDir: filepath.Dir(wtPath), FullName: filepath.Base(wtPath), Url: gitHttpUrl, Type: repoType, Alias: repoName})
-								}
-							}
-						}
-					}
-
-					*paths = addParents(*paths, rootLocation, path)
-					return filepath.SkipDir
-				}
-			}
+func appendRepo(paths []RepoData, rootLocation string, path string, resolved resolvedRepo) []RepoData {
+	repoDir, repoName := dirAndName(rootLocation, path)
+	remotes := remotesAsHTTP(resolved.remotes)
+	gitHttpUrl, locationType := "-", "local"
+	if primary, ok := primaryRemote(remotes); ok {
+		gitHttpUrl = primary.Url
+		locationType = repoType(gitHttpUrl)
+	}
+	paths = append(paths, RepoData{BaseDir: repoDir, FullName: repoName, Url: gitHttpUrl, Type: locationType, Remotes: remotes})
+
+	for _, wtPath := range resolved.worktrees {
+		if wtPath == path {
+			continue
+		}
+
+		// Check if inside rootLocation
+		rel, err := filepath.Rel(rootLocation, wtPath)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			// Inside
+			wtDir, wtName := dirAndName(rootLocation, wtPath)
+			paths = append(paths, RepoData{BaseDir: wtDir, FullName: wtName, Url: gitHttpUrl, Type: locationType, Alias: repoName, Remotes: remotes})
 		} else {
-			if strings.HasSuffix(path, ".git") {
-				file, err := os.Open(path)
-				defer file.Close()
-				if err == nil {
-
-					scanner := bufio.NewScanner(file)
-
-					if scanner.Scan() {
-						firstLine := scanner.Text()
-						re := regexp.MustCompile(`\s+`)
-						cleanedLine := re.ReplaceAllString(firstLine, ";")
-						archiveData := strings.Split(cleanedLine, ";")
-						gitHttpUrl := gitURLToHTTP(archiveData[1])
-						archiveDir, archiveName := dirAndName(rootLocation, path)
-						repoData := RepoData{BaseDir: archiveDir, FullName: archiveName, Url: gitHttpUrl, Type: "archive", Alias: ""}
-						*paths = append(*paths, repoData)
-					}
-				}
-			}
+			// Outside - use actual directory name with repo name as alias
+			paths = append(paths, RepoData{BaseDir: filepath.Dir(wtPath), FullName: filepath.Base(wtPath), Url: gitHttpUrl, Type: locationType, Alias: repoName, Remotes: remotes})
 		}
+	}
+
+	return addParents(paths, rootLocation, path)
+}
+
+func archiveRepoData(rootLocation string, path string) (RepoData, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return RepoData{}, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return RepoData{}, false
+	}
 
-		return nil
+	firstLine := scanner.Text()
+	re := regexp.MustCompile(`\s+`)
+	cleanedLine := re.ReplaceAllString(firstLine, ";")
+	archiveData := strings.Split(cleanedLine, ";")
+	if len(archiveData) < 2 {
+		return RepoData{}, false
 	}
+	gitHttpUrl := gitURLToHTTP(archiveData[1])
+	archiveDir, archiveName := dirAndName(rootLocation, path)
+	return RepoData{BaseDir: archiveDir, FullName: archiveName, Url: gitHttpUrl, Type: "archive", Alias: ""}, true
 }
 
 func deDuplicate(input []RepoData) []RepoData {
-	encountered := map[RepoData]bool{}
+	encountered := map[string]bool{}
 	result := []RepoData{}
 
 	for _, v := range input {
-		if !encountered[v] {
-			encountered[v] = true
+		key := strings.Join(v.AsCsvRecord(), ";")
+		if !encountered[key] {
+			encountered[key] = true
 			result = append(result, v)
 		}
 	}
@@ -220,16 +351,67 @@ func dirAndName(rootLocation string, path string) (string, string) {
 	}
 }
 
-func getGitRemote(dir string) (string, error) {
-	cmd := exec.Command("git", "remote", "get-url", "origin")
+// getGitRemotes returns all remotes of the repository at dir, reading its git config directly
+// and only falling back to running git when the config cannot be read.
+func getGitRemotes(dir string) ([]Remote, error) {
+	remotes, err := readGitRemotes(dir)
+	if err != nil {
+		return getGitRemotesFromGit(dir)
+	}
+	return remotes, nil
+}
+
+func getGitRemotesFromGit(dir string) ([]Remote, error) {
+	cmd := exec.Command("git", "remote", "-v")
 	cmd.Dir = dir
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", fmt.Errorf("failed to get Git remote: %v", err)
+		return nil, fmt.Errorf("failed to get Git remotes: %v", err)
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	var remotes []Remote
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && !hasRemote(remotes, fields[0]) {
+			remotes = append(remotes, Remote{Name: fields[0], Url: fields[1]})
+		}
+	}
+	return remotes, nil
+}
+
+// primaryRemote picks the remote used as the repository's main URL - origin if present, otherwise the first one.
+func primaryRemote(remotes []Remote) (Remote, bool) {
+	for _, remote := range remotes {
+		if remote.Name == "origin" {
+			return remote, true
+		}
+	}
+	if len(remotes) > 0 {
+		return remotes[0], true
+	}
+	return Remote{}, false
+}
+
+func remotesAsHTTP(remotes []Remote) []Remote {
+	var result []Remote
+	for _, remote := range remotes {
+		result = append(result, Remote{Name: remote.Name, Url: gitURLToHTTP(remote.Url)})
+	}
+	return result
+}
+
+// repoKey identifies a repository across all of its worktrees - by its shared git directory when
+// it can be resolved, otherwise by its primary remote.
+func repoKey(dir string, remotes []Remote) string {
+	if gitDir, err := resolveGitDir(dir); err == nil {
+		return commonGitDir(gitDir)
+	}
+	if primary, ok := primaryRemote(remotes); ok {
+		return primary.Url
+	}
+	return dir
 }
 
 var GIT_URL_REGEX = regexp.MustCompile(`git@([a-zA-Z0-9.-]+):`)
@@ -4,7 +4,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"testing"
+
+	csvHelper "ronkitay.com/griffin/pkg/csv"
 )
 
 func TestLocateRepos_Worktrees(t *testing.T) {
@@ -36,7 +40,7 @@ func TestLocateRepos_Worktrees(t *testing.T) {
 	wtOutside := filepath.Join(root2, "wt-outside")
 	runGit(t, repoDir, "worktree", "add", "--detach", wtOutside, "master")
 
-	repos := locateRepos(root1, make(map[string]struct{}))
+	repos := locateRepos([]string{root1}, 4, newIndexState())
 
 	foundRepo := false
 	foundWtInside := false
@@ -69,6 +73,134 @@ func TestLocateRepos_Worktrees(t *testing.T) {
 	}
 }
 
+func TestLocateRepos_DeterministicOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	root1 := filepath.Join(tmpDir, "root1")
+	root2 := filepath.Join(tmpDir, "root2")
+	for _, name := range []string{"group/b-repo", "group/a-repo", "c-repo"} {
+		for i, root := range []string{root1, root2} {
+			repoDir := filepath.Join(root, name)
+			if err := os.MkdirAll(repoDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			runGit(t, repoDir, "init")
+			runGit(t, repoDir, "remote", "add", "origin", "git@github.com:user/"+filepath.Base(name)+string(rune('0'+i))+".git")
+		}
+	}
+
+	serial := locateRepos([]string{root1, root2}, 1, newIndexState())
+	parallel := locateRepos([]string{root1, root2}, 8, newIndexState())
+
+	if len(serial) != 10 {
+		t.Fatalf("Expected 10 entries (6 repos, 2 roots and 2 group dirs), got %d: %v", len(serial), serial)
+	}
+	if !reflect.DeepEqual(serial, parallel) {
+		t.Errorf("Expected parallel result to match serial result.\nSerial:   %v\nParallel: %v", serial, parallel)
+	}
+	if serial[0].BaseDir != root1 || serial[len(serial)-1].BaseDir != root2 {
+		t.Errorf("Expected repos to be ordered by root: %v", serial)
+	}
+}
+
+func TestLocateRepos_AllRemotes(t *testing.T) {
+	root := t.TempDir()
+
+	forkDir := filepath.Join(root, "fork")
+	os.MkdirAll(forkDir, 0755)
+	runGit(t, forkDir, "init")
+	runGit(t, forkDir, "remote", "add", "upstream", "git@gitlab.com:acme/fork.git")
+	runGit(t, forkDir, "remote", "add", "mine", "https://github.com/me/fork.git")
+
+	internalDir := filepath.Join(root, "internal")
+	os.MkdirAll(internalDir, 0755)
+	runGit(t, internalDir, "init")
+	runGit(t, internalDir, "remote", "add", "upstream", "https://git.acme.internal/acme/internal.git")
+
+	localDir := filepath.Join(root, "local")
+	os.MkdirAll(localDir, 0755)
+	runGit(t, localDir, "init")
+
+	repos := locateRepos([]string{root}, 2, newIndexState())
+
+	reposByName := map[string]RepoData{}
+	for _, r := range repos {
+		reposByName[r.FullName] = r
+	}
+
+	fork, ok := reposByName["fork"]
+	if !ok {
+		t.Fatalf("Expected repo without origin to be indexed: %v", repos)
+	}
+	expectedRemotes := []Remote{{Name: "upstream", Url: "https://gitlab.com/acme/fork"}, {Name: "mine", Url: "https://github.com/me/fork"}}
+	if !reflect.DeepEqual(fork.Remotes, expectedRemotes) {
+		t.Errorf("Expected remotes %v, got %v", expectedRemotes, fork.Remotes)
+	}
+	if fork.Url != "https://gitlab.com/acme/fork" || fork.Type != "gitlab" {
+		t.Errorf("Expected first remote to be the primary one, got %s (%s)", fork.Url, fork.Type)
+	}
+
+	internal, ok := reposByName["internal"]
+	if !ok || internal.Type != "unknown" || internal.Url != "https://git.acme.internal/acme/internal" {
+		t.Fatalf("Expected a repo with a remote on another host to be indexed, got %v", repos)
+	}
+	indexPath := filepath.Join(t.TempDir(), "repos.csv")
+	if err := csvHelper.SaveIndex(indexPath, INDEX_SCHEMA, repos); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := csvHelper.LoadIndex(indexPath, INDEX_SCHEMA, converter(false, false), t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(loaded, func(repo RepoData) bool { return reflect.DeepEqual(repo, internal) }) {
+		t.Errorf("Expected %v to be loaded from the index, got %v", internal, loaded)
+	}
+
+	local, ok := reposByName["local"]
+	if !ok {
+		t.Fatalf("Expected repo without remotes to be indexed: %v", repos)
+	}
+	if local.Type != "local" || local.Url != "-" {
+		t.Errorf("Expected a local repo, got %v", local)
+	}
+}
+
+func TestConverter_Remotes(t *testing.T) {
+	repo := RepoData{BaseDir: "/src", FullName: "fork", Url: "https://gitlab.com/acme/fork", Type: "gitlab",
+		Remotes: []Remote{{Name: "upstream", Url: "https://gitlab.com/acme/fork"}, {Name: "mine", Url: "https://github.com/me/fork,v2=x"}}}
+
+	loaded, err := converter(false, false)(asIndexRecord(repo))
+	if err != nil {
+		t.Fatalf("converter failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, repo) {
+		t.Errorf("Expected %v, got %v", repo, loaded)
+	}
+
+	legacy, err := converter(false, false)(csvHelper.IndexRecord{"baseDir": "/src", "name": "old", "url": "https://github.com/me/old", "type": "github"})
+	if err != nil {
+		t.Fatalf("converter failed on legacy record: %v", err)
+	}
+	if legacy.Remotes != nil {
+		t.Errorf("Expected no remotes for a legacy record, got %v", legacy.Remotes)
+	}
+
+	legacy, err = converter(false, false)(csvHelper.IndexRecord{"baseDir": "/src", "name": "old", "url": "https://github.com/me/old", "type": "github",
+		"remotes": "origin=https://github.com/me/old,mine=https://github.com/you/old"})
+	expectedRemotes := []Remote{{Name: "origin", Url: "https://github.com/me/old"}, {Name: "mine", Url: "https://github.com/you/old"}}
+	if err != nil || !reflect.DeepEqual(legacy.Remotes, expectedRemotes) {
+		t.Errorf("Expected the name=url remotes of older indexes to be read, got %v (%v)", legacy.Remotes, err)
+	}
+}
+
+func asIndexRecord(repo RepoData) csvHelper.IndexRecord {
+	record := csvHelper.IndexRecord{}
+	for i, value := range repo.AsCsvRecord() {
+		record[INDEX_SCHEMA.Columns[i]] = value
+	}
+	return record
+}
+
 func TestGetWorktrees(t *testing.T) {
 	tmpDir := t.TempDir()
 	repoDir := filepath.Join(tmpDir, "repo")
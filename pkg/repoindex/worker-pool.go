@@ -0,0 +1,40 @@
+package repoindex
+
+import (
+	"runtime"
+	"sync"
+)
+
+// DefaultJobs is the number of workers used when no explicit value is given.
+func DefaultJobs() int {
+	return runtime.NumCPU()
+}
+
+// parallelMap applies fn to every item using at most jobs concurrent workers.
+// Results are returned in the same order as the input items.
+func parallelMap[T any, R any](jobs int, items []T, fn func(T) R) []R {
+	results := make([]R, len(items))
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < len(items); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = fn(items[i])
+			}
+		}()
+	}
+
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,320 @@
+// Package rpc lets editor plugins query griffin with JSON-RPC 2.0 over stdin/stdout, framed like the Language Server
+// Protocol - every message is preceded by a `Content-Length: <bytes>` header and an empty line.
+package rpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	csvHelper "ronkitay.com/griffin/pkg/csv"
+	"ronkitay.com/griffin/pkg/finder"
+	"ronkitay.com/griffin/pkg/griffin"
+	"ronkitay.com/griffin/pkg/matcher"
+)
+
+// JSON-RPC error codes, including the griffin specific ones
+const (
+	CODE_PARSE_ERROR      = -32700
+	CODE_INVALID_REQUEST  = -32600
+	CODE_METHOD_NOT_FOUND = -32601
+	CODE_INVALID_PARAMS   = -32602
+	CODE_INTERNAL_ERROR   = -32603
+	CODE_MISSING_INDEX    = -32001
+	CODE_INVALID_QUERY    = -32002
+)
+
+type request struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *responseError  `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *responseError) Error() string {
+	return err.Message
+}
+
+// SearchParams are the params of searchRepos and searchProjects.
+// The query uses the find-repo/find-project syntax, e.g. `api type:gitlab`.
+type SearchParams struct {
+	Query        string `json:"query"`
+	Regex        bool   `json:"regex"`
+	NoHistory    bool   `json:"noHistory"`
+	NoArchives   bool   `json:"noArchives"`
+	NoDirs       bool   `json:"noDirs"`
+	MatchRemotes bool   `json:"matchRemotes"`
+	Limit        int    `json:"limit"`
+}
+
+// PathParams are the params of resolveRepo and listProjects.
+type PathParams struct {
+	Path string `json:"path"`
+}
+
+// OpenParams are the params of open.
+type OpenParams struct {
+	Path        string `json:"path"`
+	Alternative bool   `json:"alternative"`
+}
+
+type handler func(client *griffin.Client, params json.RawMessage) (any, error)
+
+// METHODS are the supported methods. Besides them, the server answers the initialize, shutdown and exit
+// lifecycle messages of the Language Server Protocol.
+var METHODS = map[string]handler{
+	"searchRepos":    searchRepos,
+	"searchProjects": searchProjects,
+	"resolveRepo":    resolveRepo,
+	"listProjects":   listProjects,
+	"open":           open,
+}
+
+// Serve answers the requests read from input until it is closed or an exit notification is received.
+func Serve(client *griffin.Client, input io.Reader, output io.Writer) error {
+	reader := bufio.NewReader(input)
+	for {
+		message, err := readMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var call request
+		if err := json.Unmarshal(message, &call); err != nil {
+			if err := writeMessage(output, response{JsonRpc: "2.0", Id: json.RawMessage("null"), Error: &responseError{Code: CODE_PARSE_ERROR, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if call.Method == "exit" {
+			return nil
+		}
+
+		result, callErr := dispatch(client, call)
+		// Notifications (requests without an id) are never answered
+		if len(call.Id) == 0 {
+			continue
+		}
+
+		reply := response{JsonRpc: "2.0", Id: call.Id, Result: result}
+		if callErr != nil {
+			reply.Result = nil
+			reply.Error = asResponseError(callErr)
+		} else if result == nil {
+			reply.Result = json.RawMessage("null")
+		}
+		if err := writeMessage(output, reply); err != nil {
+			return err
+		}
+	}
+}
+
+func dispatch(client *griffin.Client, call request) (any, error) {
+	if call.JsonRpc != "2.0" || call.Method == "" {
+		return nil, &responseError{Code: CODE_INVALID_REQUEST, Message: "invalid JSON-RPC 2.0 request"}
+	}
+
+	switch call.Method {
+	case "initialize":
+		var methods []string
+		for method := range METHODS {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		return map[string]any{"serverInfo": map[string]string{"name": "griffin"}, "methods": methods}, nil
+	case "shutdown":
+		return nil, nil
+	}
+
+	method, found := METHODS[call.Method]
+	if !found {
+		return nil, &responseError{Code: CODE_METHOD_NOT_FOUND, Message: "method not found: " + call.Method}
+	}
+	return method(client, call.Params)
+}
+
+func asResponseError(err error) *responseError {
+	var rpcError *responseError
+	var missingIndexError *csvHelper.MissingIndexError
+	var invalidPatternError *matcher.InvalidPatternError
+	var unknownFieldError *matcher.UnknownFieldError
+
+	switch {
+	case errors.As(err, &rpcError):
+		return rpcError
+	case errors.As(err, &missingIndexError):
+		return &responseError{Code: CODE_MISSING_INDEX, Message: err.Error()}
+	case errors.As(err, &invalidPatternError), errors.As(err, &unknownFieldError):
+		return &responseError{Code: CODE_INVALID_QUERY, Message: err.Error()}
+	default:
+		return &responseError{Code: CODE_INTERNAL_ERROR, Message: err.Error()}
+	}
+}
+
+func decodeParams[P any](raw json.RawMessage) (P, error) {
+	var params P
+	if len(raw) == 0 {
+		return params, nil
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return params, &responseError{Code: CODE_INVALID_PARAMS, Message: err.Error()}
+	}
+	return params, nil
+}
+
+func searchRepos(client *griffin.Client, raw json.RawMessage) (any, error) {
+	params, err := decodeParams[SearchParams](raw)
+	if err != nil {
+		return nil, err
+	}
+
+	repos, err := client.FindRepos(griffin.RepoSearchOptions{
+		SearchOptions: griffin.SearchOptions{Regex: params.Regex, NoHistory: params.NoHistory},
+		NoArchives:    params.NoArchives,
+		NoDirs:        params.NoDirs,
+		MatchRemotes:  params.MatchRemotes,
+	}, strings.Fields(params.Query))
+	if err != nil {
+		return nil, err
+	}
+
+	records := []finder.RepoRecord{}
+	for i, repo := range repos {
+		if params.Limit > 0 && i == params.Limit {
+			break
+		}
+		records = append(records, finder.NewRepoRecord(repo))
+	}
+	return records, nil
+}
+
+func searchProjects(client *griffin.Client, raw json.RawMessage) (any, error) {
+	params, err := decodeParams[SearchParams](raw)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := client.FindProjects(griffin.SearchOptions{Regex: params.Regex, NoHistory: params.NoHistory}, strings.Fields(params.Query))
+	if err != nil {
+		return nil, err
+	}
+
+	records := []finder.ProjectRecord{}
+	for i, project := range projects {
+		if params.Limit > 0 && i == params.Limit {
+			break
+		}
+		records = append(records, finder.NewProjectRecord(project))
+	}
+	return records, nil
+}
+
+// resolveRepo returns the repository containing the path, or null if it is not in an indexed repository
+func resolveRepo(client *griffin.Client, raw json.RawMessage) (any, error) {
+	params, err := decodeParams[PathParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	if params.Path == "" {
+		return nil, &responseError{Code: CODE_INVALID_PARAMS, Message: "path is required"}
+	}
+
+	repo, found, err := client.RepoOf(params.Path)
+	if err != nil || !found {
+		return nil, err
+	}
+	return finder.NewRepoRecord(matcher.Ranked[griffin.Repo]{Item: repo}), nil
+}
+
+// listProjects returns the projects of the repository containing the path
+func listProjects(client *griffin.Client, raw json.RawMessage) (any, error) {
+	params, err := decodeParams[PathParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	if params.Path == "" {
+		return nil, &responseError{Code: CODE_INVALID_PARAMS, Message: "path is required"}
+	}
+
+	projects, err := client.ProjectsIn(params.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []finder.ProjectRecord{}
+	for _, project := range projects {
+		records = append(records, finder.NewProjectRecord(matcher.Ranked[griffin.Project]{Item: project}))
+	}
+	return records, nil
+}
+
+func open(client *griffin.Client, raw json.RawMessage) (any, error) {
+	params, err := decodeParams[OpenParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(params.Path); err != nil {
+		return nil, &responseError{Code: CODE_INVALID_PARAMS, Message: "path does not exist: " + params.Path}
+	}
+
+	if params.Alternative {
+		err = client.OpenInAlternativeIDE(params.Path)
+	} else {
+		err = client.OpenInIDE(params.Path)
+	}
+	return nil, err
+}
+
+// readMessage reads the next message, skipping its headers
+func readMessage(reader *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || (errors.Is(err, io.ErrUnexpectedEOF) && len(headers) == 0) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header: %q", headers.Get("Content-Length"))
+	}
+
+	message := make([]byte, length)
+	if _, err := io.ReadFull(reader, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func writeMessage(writer io.Writer, message response) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
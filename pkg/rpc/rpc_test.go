@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	config "ronkitay.com/griffin/pkg/configuration"
+	csvHelper "ronkitay.com/griffin/pkg/csv"
+	"ronkitay.com/griffin/pkg/griffin"
+	projectIndex "ronkitay.com/griffin/pkg/projectindex"
+	repoIndex "ronkitay.com/griffin/pkg/repoindex"
+)
+
+func newTestClient(t *testing.T) *griffin.Client {
+	configuration := config.NewConfiguration(t.TempDir(), config.UserConfiguration{})
+	repos := []repoIndex.RepoData{
+		{BaseDir: "/src", FullName: "api", Url: "https://gitlab.com/acme/api", Type: "gitlab"},
+		{BaseDir: "/src/api", FullName: "vendored", Url: "-", Type: "local"},
+		{BaseDir: "/src", FullName: "website", Url: "https://github.com/acme/website", Type: "github"},
+	}
+	projects := []projectIndex.ProjectData{
+		{BaseDir: "/src/api", FullName: "server", Type: "go"},
+		{BaseDir: "/src/api", FullName: "client", Type: "node"},
+		{BaseDir: "/src/api/vendored", FullName: "lib", Type: "go"},
+		{BaseDir: "/src/website", FullName: "site", Type: "node"},
+	}
+	if err := csvHelper.SaveIndex(configuration.RepoListLocation, repoIndex.INDEX_SCHEMA, repos); err != nil {
+		t.Fatal(err)
+	}
+	if err := csvHelper.SaveIndex(configuration.ProjectListLocation, projectIndex.INDEX_SCHEMA, projects); err != nil {
+		t.Fatal(err)
+	}
+	return griffin.NewClient(configuration)
+}
+
+func frame(messages ...string) io.Reader {
+	var input bytes.Buffer
+	for _, message := range messages {
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(message), message)
+	}
+	return &input
+}
+
+type testResponse struct {
+	Id     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+func call(t *testing.T, client *griffin.Client, messages ...string) []testResponse {
+	var output bytes.Buffer
+	if err := Serve(client, frame(messages...), &output); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	var responses []testResponse
+	reader := bufio.NewReader(&output)
+	for {
+		message, err := readMessage(reader)
+		if err == io.EOF {
+			return responses
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reply testResponse
+		if err := json.Unmarshal(message, &reply); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, reply)
+	}
+}
+
+func TestServe_Methods(t *testing.T) {
+	responses := call(t, newTestClient(t),
+		`{"jsonrpc":"2.0","id":1,"method":"searchRepos","params":{"query":"web","noHistory":true}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"resolveRepo","params":{"path":"/src/api/vendored/lib/main.go"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"listProjects","params":{"path":"/src/api"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resolveRepo","params":{"path":"/elsewhere"}}`,
+	)
+
+	if len(responses) != 4 {
+		t.Fatalf("Expected a response per request (and none for the notification), got %v", responses)
+	}
+	expected := []string{
+		`[{"path":"/src/website","baseDir":"/src","name":"website","url":"https://github.com/acme/website","type":"github","alias":"","remotes":[],"score":`,
+		`{"path":"/src/api/vendored","baseDir":"/src/api","name":"vendored",`,
+		`[{"path":"/src/api/server","baseDir":"/src/api","name":"server","language":"go","languages":["go"],"score":0},{"path":"/src/api/client","baseDir":"/src/api","name":"client","language":"node","languages":["node"],"score":0}]`,
+		`null`,
+	}
+	for i, response := range responses {
+		if response.Id != i+1 || response.Error != nil || !bytes.HasPrefix(response.Result, []byte(expected[i])) {
+			t.Errorf("Expected response %d to start with %s, got %s (error %v)", i+1, expected[i], response.Result, response.Error)
+		}
+	}
+}
+
+func TestServe_Errors(t *testing.T) {
+	responses := call(t, newTestClient(t),
+		`{"jsonrpc":"2.0","id":1,"method":"rename"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"searchRepos","params":{"query":"lang:go"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"listProjects","params":{"path":42}}`,
+		`{not json`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"searchRepos"}`,
+	)
+
+	expectedCodes := []int{CODE_METHOD_NOT_FOUND, CODE_INVALID_QUERY, CODE_INVALID_PARAMS, CODE_PARSE_ERROR}
+	if len(responses) != len(expectedCodes) {
+		t.Fatalf("Expected no responses after exit, got %v", responses)
+	}
+	for i, response := range responses {
+		if response.Error == nil || response.Error.Code != expectedCodes[i] {
+			t.Errorf("Expected error code %d, got %v", expectedCodes[i], response.Error)
+		}
+	}
+}
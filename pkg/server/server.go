@@ -0,0 +1,298 @@
+// Package server answers griffin queries over HTTP with JSON responses, for editors and launchers that would
+// otherwise run `griffin find-repo` (and reload the indexes) on every keystroke.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	csvHelper "ronkitay.com/griffin/pkg/csv"
+	"ronkitay.com/griffin/pkg/finder"
+	"ronkitay.com/griffin/pkg/griffin"
+	"ronkitay.com/griffin/pkg/matcher"
+)
+
+const (
+	NETWORK_UNIX = "unix"
+	NETWORK_TCP  = "tcp"
+)
+
+// Serve answers queries on a Unix socket or a loopback TCP address until the process is interrupted.
+// The indexes are kept in memory by the client and reloaded whenever their files change.
+func Serve(client *griffin.Client, network string, address string) error {
+	listener, err := listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	// Load the indexes up front, so the first query is as fast as the others
+	client.Repos(false, false)
+	client.Projects()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpServer := &http.Server{Handler: NewHandler(client)}
+	serveErrors := make(chan error, 1)
+	go func() {
+		serveErrors <- httpServer.Serve(listener)
+	}()
+
+	fmt.Printf("Serving on %s:%s\n", network, address)
+
+	select {
+	case err := <-serveErrors:
+		return err
+	case <-ctx.Done():
+		return httpServer.Shutdown(context.Background())
+	}
+}
+
+// listen only accepts loopback TCP addresses - the server is meant for the local user alone.
+func listen(network string, address string) (net.Listener, error) {
+	switch network {
+	case NETWORK_UNIX:
+		// A socket left behind by a server that was killed would fail the listen
+		if info, err := os.Lstat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(address)
+		}
+		listener, err := net.Listen(NETWORK_UNIX, address)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(address, 0600); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		return listener, nil
+	case NETWORK_TCP:
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("refusing to listen on %s - only loopback addresses are supported", address)
+		}
+		return net.Listen(NETWORK_TCP, address)
+	default:
+		return nil, fmt.Errorf("unsupported network: %s", network)
+	}
+}
+
+// NewHandler serves:
+//
+//	GET  /repos?q=<query>     repositories matching the query (all of them without one), best match first
+//	GET  /projects?q=<query>  projects matching the query, best match first
+//	POST /open                opens {"path": "...", "alternative": false} in an IDE
+//
+// Queries use the find-repo/find-project syntax, e.g. `q=api type:gitlab`. Both searches take the regex, noHistory
+// and limit parameters, and /repos also takes noArchives, noDirs and matchRemotes.
+//
+// Over TCP, only requests to a loopback host are answered, so web pages cannot reach the server through DNS
+// rebinding, and /open only accepts application/json bodies, which browsers cannot send to it without a preflight.
+func NewHandler(client *griffin.Client) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos", func(writer http.ResponseWriter, request *http.Request) {
+		handleRepos(client, writer, request)
+	})
+	mux.HandleFunc("GET /projects", func(writer http.ResponseWriter, request *http.Request) {
+		handleProjects(client, writer, request)
+	})
+	mux.HandleFunc("POST /open", func(writer http.ResponseWriter, request *http.Request) {
+		handleOpen(client, writer, request)
+	})
+	return loopbackOnly(mux)
+}
+
+// loopbackOnly rejects TCP requests whose Host is not a loopback address
+func loopbackOnly(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		localAddress, _ := request.Context().Value(http.LocalAddrContextKey).(net.Addr)
+		if localAddress != nil && localAddress.Network() == NETWORK_TCP && !isLoopbackHost(request.Host) {
+			writeError(writer, http.StatusForbidden, fmt.Errorf("host not allowed: %s", request.Host))
+			return
+		}
+		handler.ServeHTTP(writer, request)
+	})
+}
+
+func isLoopbackHost(hostAndPort string) bool {
+	host, _, err := net.SplitHostPort(hostAndPort)
+	if err != nil {
+		host = hostAndPort
+	}
+	host = strings.Trim(host, "[]")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback()
+	}
+	return host == "localhost"
+}
+
+func handleRepos(client *griffin.Client, writer http.ResponseWriter, request *http.Request) {
+	params := queryParams{values: request.URL.Query()}
+	options := griffin.RepoSearchOptions{
+		SearchOptions: params.searchOptions(),
+		NoArchives:    params.boolParam("noArchives"),
+		NoDirs:        params.boolParam("noDirs"),
+		MatchRemotes:  params.boolParam("matchRemotes"),
+	}
+	limit := params.intParam("limit")
+	if params.err != nil {
+		writeError(writer, http.StatusBadRequest, params.err)
+		return
+	}
+
+	repos, err := client.FindRepos(options, params.query())
+	if err != nil {
+		writeError(writer, statusOf(err), err)
+		return
+	}
+
+	records := []finder.RepoRecord{}
+	for _, repo := range limitResults(repos, limit) {
+		records = append(records, finder.NewRepoRecord(repo))
+	}
+	writeJSON(writer, http.StatusOK, records)
+}
+
+func handleProjects(client *griffin.Client, writer http.ResponseWriter, request *http.Request) {
+	params := queryParams{values: request.URL.Query()}
+	options := params.searchOptions()
+	limit := params.intParam("limit")
+	if params.err != nil {
+		writeError(writer, http.StatusBadRequest, params.err)
+		return
+	}
+
+	projects, err := client.FindProjects(options, params.query())
+	if err != nil {
+		writeError(writer, statusOf(err), err)
+		return
+	}
+
+	records := []finder.ProjectRecord{}
+	for _, project := range limitResults(projects, limit) {
+		records = append(records, finder.NewProjectRecord(project))
+	}
+	writeJSON(writer, http.StatusOK, records)
+}
+
+type openRequest struct {
+	Path        string `json:"path"`
+	Alternative bool   `json:"alternative"`
+}
+
+func handleOpen(client *griffin.Client, writer http.ResponseWriter, request *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(writer, http.StatusUnsupportedMediaType, fmt.Errorf("expected an application/json body"))
+		return
+	}
+
+	var body openRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeError(writer, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+	if _, err := os.Stat(body.Path); err != nil {
+		writeError(writer, http.StatusBadRequest, fmt.Errorf("path does not exist: %s", body.Path))
+		return
+	}
+
+	var err error
+	if body.Alternative {
+		err = client.OpenInAlternativeIDE(body.Path)
+	} else {
+		err = client.OpenInIDE(body.Path)
+	}
+	if err != nil {
+		writeError(writer, statusOf(err), err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, map[string]string{"opened": body.Path})
+}
+
+// queryParams reads URL parameters, keeping the first invalid one as err
+type queryParams struct {
+	values map[string][]string
+	err    error
+}
+
+// query splits the q parameters into the arguments find-repo would get
+func (params *queryParams) query() []string {
+	var args []string
+	for _, q := range params.values["q"] {
+		args = append(args, strings.Fields(q)...)
+	}
+	return args
+}
+
+func (params *queryParams) searchOptions() griffin.SearchOptions {
+	return griffin.SearchOptions{Regex: params.boolParam("regex"), NoHistory: params.boolParam("noHistory")}
+}
+
+func (params *queryParams) boolParam(name string) bool {
+	values := params.values[name]
+	if len(values) == 0 {
+		return false
+	}
+	value, err := strconv.ParseBool(values[0])
+	if err != nil && params.err == nil {
+		params.err = fmt.Errorf("invalid value for %s: %s", name, values[0])
+	}
+	return value
+}
+
+func (params *queryParams) intParam(name string) int {
+	values := params.values[name]
+	if len(values) == 0 {
+		return 0
+	}
+	value, err := strconv.Atoi(values[0])
+	if (err != nil || value < 0) && params.err == nil {
+		params.err = fmt.Errorf("invalid value for %s: %s", name, values[0])
+	}
+	return value
+}
+
+// limitResults keeps the first limit results, or all of them when limit is 0
+func limitResults[T any](results []matcher.Ranked[T], limit int) []matcher.Ranked[T] {
+	if limit > 0 && len(results) > limit {
+		return results[:limit]
+	}
+	return results
+}
+
+func statusOf(err error) int {
+	var missingIndexError *csvHelper.MissingIndexError
+	var invalidPatternError *matcher.InvalidPatternError
+	var unknownFieldError *matcher.UnknownFieldError
+
+	switch {
+	case errors.As(err, &missingIndexError):
+		return http.StatusNotFound
+	case errors.As(err, &invalidPatternError), errors.As(err, &unknownFieldError):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(writer http.ResponseWriter, status int, err error) {
+	writeJSON(writer, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(value)
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	config "ronkitay.com/griffin/pkg/configuration"
+	csvHelper "ronkitay.com/griffin/pkg/csv"
+	"ronkitay.com/griffin/pkg/finder"
+	"ronkitay.com/griffin/pkg/griffin"
+	repoIndex "ronkitay.com/griffin/pkg/repoindex"
+)
+
+func saveRepos(t *testing.T, configuration config.Configuration, repos ...repoIndex.RepoData) {
+	if err := csvHelper.SaveIndex(configuration.RepoListLocation, repoIndex.INDEX_SCHEMA, repos); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getRepos(t *testing.T, server *httptest.Server, query string) (int, []finder.RepoRecord) {
+	response, err := http.Get(server.URL + "/repos?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var records []finder.RepoRecord
+	if response.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(response.Body).Decode(&records); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return response.StatusCode, records
+}
+
+func TestRepos_SearchesAndReloads(t *testing.T) {
+	configuration := config.NewConfiguration(t.TempDir(), config.UserConfiguration{})
+	saveRepos(t, configuration,
+		repoIndex.RepoData{BaseDir: "/src", FullName: "api-gateway", Url: "https://gitlab.com/acme/api-gateway", Type: "gitlab"},
+		repoIndex.RepoData{BaseDir: "/src", FullName: "website", Url: "https://github.com/acme/website", Type: "github"},
+	)
+
+	server := httptest.NewServer(NewHandler(griffin.NewClient(configuration)))
+	defer server.Close()
+
+	status, records := getRepos(t, server, "q="+url.QueryEscape("gw type:gitlab"))
+	if status != http.StatusOK || len(records) != 1 || records[0].Path != "/src/api-gateway" {
+		t.Errorf("Expected the api-gateway repo, got %d %v", status, records)
+	}
+
+	if status, records = getRepos(t, server, "limit=1"); status != http.StatusOK || len(records) != 1 {
+		t.Errorf("Expected a single repo with limit=1, got %d %v", status, records)
+	}
+
+	// A rebuilt index is picked up without restarting the server
+	saveRepos(t, configuration, repoIndex.RepoData{BaseDir: "/src", FullName: "gateway-v2", Url: "https://gitlab.com/acme/gateway-v2", Type: "gitlab"})
+	if status, records = getRepos(t, server, "q=gw"); status != http.StatusOK || len(records) != 1 || records[0].FullName != "gateway-v2" {
+		t.Errorf("Expected the reloaded index, got %d %v", status, records)
+	}
+}
+
+func TestRepos_Errors(t *testing.T) {
+	configuration := config.NewConfiguration(t.TempDir(), config.UserConfiguration{})
+	server := httptest.NewServer(NewHandler(griffin.NewClient(configuration)))
+	defer server.Close()
+
+	if status, _ := getRepos(t, server, "q=api"); status != http.StatusNotFound {
+		t.Errorf("Expected 404 without an index, got %d", status)
+	}
+
+	saveRepos(t, configuration, repoIndex.RepoData{BaseDir: "/src", FullName: "api", Url: "-", Type: "local"})
+	if status, _ := getRepos(t, server, "q=lang:go"); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown field, got %d", status)
+	}
+	if status, _ := getRepos(t, server, "regex=maybe"); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid parameter, got %d", status)
+	}
+}
+
+func TestHandler_RejectsCrossSiteRequests(t *testing.T) {
+	configuration := config.NewConfiguration(t.TempDir(), config.UserConfiguration{})
+	saveRepos(t, configuration, repoIndex.RepoData{BaseDir: "/src", FullName: "api", Url: "-", Type: "local"})
+	server := httptest.NewServer(NewHandler(griffin.NewClient(configuration)))
+	defer server.Close()
+
+	// A page on a rebound domain reaches the server with its own Host
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/repos", nil)
+	request.Host = "attacker.example:8080"
+	if response, err := http.DefaultClient.Do(request); err != nil || response.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a foreign host to be rejected, got %v %v", response, err)
+	}
+
+	// A form or fetch without a preflight can only send text/plain
+	response, err := http.Post(server.URL+"/open", "text/plain", strings.NewReader(`{"path": "/"}`))
+	if err != nil || response.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected a text/plain body to be rejected, got %v %v", response, err)
+	}
+	response, err = http.Post(server.URL+"/open", "application/json; charset=utf-8", strings.NewReader(`{"path": "/missing"}`))
+	if err != nil || response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a JSON body to be accepted, got %v %v", response, err)
+	}
+}
+
+func TestListen_OnlyLoopback(t *testing.T) {
+	if _, err := listen(NETWORK_TCP, "0.0.0.0:0"); err == nil {
+		t.Errorf("Expected listening on all interfaces to be refused")
+	}
+
+	listener, err := listen(NETWORK_TCP, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected listening on loopback to succeed: %v", err)
+	}
+	listener.Close()
+}
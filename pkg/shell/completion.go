@@ -0,0 +1,182 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// The completion scripts of each shell, as text/template templates executed with a completionData.
+// They all ask `griffin __complete <words>` for the candidates, passing the words after `griffin` - the last one
+// being the word completed, which may be empty. Functions is set when the integration functions are completed too.
+
+const BASH_COMPLETION_TEMPLATE = `
+_griffin_complete() {
+	local IFS=$'\n'
+	COMPREPLY=($(griffin __complete "${COMP_WORDS[@]:1:COMP_CWORD}"))
+}
+complete -o default -F _griffin_complete griffin
+{{- if .Functions}}
+
+_griffin_complete_repos() {
+	local IFS=$'\n'
+	COMPREPLY=($(griffin __complete find-repo "${COMP_WORDS[COMP_CWORD]}"))
+}
+
+_griffin_complete_projects() {
+	local IFS=$'\n'
+	COMPREPLY=($(griffin __complete find-project "${COMP_WORDS[COMP_CWORD]}"))
+}
+
+complete -F _griffin_complete_repos {{.Functions.SwitchToRepo}} {{.Functions.OpenRepo}}
+complete -F _griffin_complete_projects {{.Functions.SwitchToProject}} {{.Functions.OpenProject}}
+{{- end}}
+`
+
+// ZSH_COMPLETION_TEMPLATE needs compinit to be loaded first
+const ZSH_COMPLETION_TEMPLATE = `
+_griffin() {
+	local -a candidates
+	candidates=("${(@f)$(griffin __complete "${(@)words[2,CURRENT]}")}")
+	candidates=("${(@)candidates:#}")
+	if (( ${#candidates} )); then
+		compadd -U -- "${candidates[@]}"
+	else
+		_files
+	fi
+}
+{{- if .Functions}}
+
+_griffin_repos() {
+	local -a candidates
+	candidates=("${(@f)$(griffin __complete find-repo "${words[CURRENT]}")}")
+	compadd -U -- "${(@)candidates:#}"
+}
+
+_griffin_projects() {
+	local -a candidates
+	candidates=("${(@f)$(griffin __complete find-project "${words[CURRENT]}")}")
+	compadd -U -- "${(@)candidates:#}"
+}
+{{- end}}
+
+if (( $+functions[compdef] )); then
+	compdef _griffin griffin
+{{- if .Functions}}
+	compdef _griffin_repos {{.Functions.SwitchToRepo}} {{.Functions.OpenRepo}}
+	compdef _griffin_projects {{.Functions.SwitchToProject}} {{.Functions.OpenProject}}
+{{- end}}
+fi
+`
+
+// FISH_COMPLETION_TEMPLATE passes the current word quoted, so an empty one is passed too
+const FISH_COMPLETION_TEMPLATE = `
+function __griffin_complete
+    set -l words (commandline -opc)
+    set -e words[1]
+    set -l current (commandline -ct)
+    griffin __complete $words "$current"
+end
+
+complete -c griffin -f -n 'not __fish_seen_subcommand_from open-in-ide record' -a '(__griffin_complete)'
+complete -c griffin -F -n '__fish_seen_subcommand_from open-in-ide record'
+{{- if .Functions}}
+
+function __griffin_complete_index --argument-names command
+    set -l current (commandline -ct)
+    griffin __complete $command "$current"
+end
+
+complete -c {{.Functions.SwitchToRepo}} -f -a '(__griffin_complete_index find-repo)'
+complete -c {{.Functions.OpenRepo}} -f -a '(__griffin_complete_index find-repo)'
+complete -c {{.Functions.SwitchToProject}} -f -a '(__griffin_complete_index find-project)'
+complete -c {{.Functions.OpenProject}} -f -a '(__griffin_complete_index find-project)'
+{{- end}}
+`
+
+// NUSHELL_COMPLETION_TEMPLATE completes griffin with an external completer, falling back to the one already configured
+// for other commands
+const NUSHELL_COMPLETION_TEMPLATE = `
+let griffin_fallback_completer = ($env.config.completions.external.completer? | default null)
+$env.config.completions.external.completer = {|spans|
+    if ($spans | first) == "griffin" {
+        griffin __complete ...($spans | skip 1) | lines
+    } else if $griffin_fallback_completer != null {
+        do $griffin_fallback_completer $spans
+    }
+}
+{{- if .Functions}}
+
+def "nu-complete griffin repos" [context: string] {
+    griffin __complete find-repo ($context | split row ' ' | last) | lines
+}
+
+def "nu-complete griffin projects" [context: string] {
+    griffin __complete find-project ($context | split row ' ' | last) | lines
+}
+{{- end}}
+`
+
+const POWERSHELL_COMPLETION_TEMPLATE = `
+Register-ArgumentCompleter -Native -CommandName griffin -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') { $words += '' }
+    griffin __complete @words | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+{{- if .Functions}}
+
+Register-ArgumentCompleter -CommandName {{.Functions.SwitchToRepo}}, {{.Functions.OpenRepo}} -ParameterName Query -ScriptBlock {
+    param($commandName, $parameterName, $wordToComplete, $commandAst, $fakeBoundParameters)
+    griffin __complete find-repo "$wordToComplete" | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+
+Register-ArgumentCompleter -CommandName {{.Functions.SwitchToProject}}, {{.Functions.OpenProject}} -ParameterName Query -ScriptBlock {
+    param($commandName, $parameterName, $wordToComplete, $commandAst, $fakeBoundParameters)
+    griffin __complete find-project "$wordToComplete" | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+{{- end}}
+`
+
+var COMPLETION_TEMPLATES = map[string]string{
+	SHELL_BASH:       BASH_COMPLETION_TEMPLATE,
+	SHELL_ZSH:        ZSH_COMPLETION_TEMPLATE,
+	SHELL_FISH:       FISH_COMPLETION_TEMPLATE,
+	SHELL_NUSHELL:    NUSHELL_COMPLETION_TEMPLATE,
+	SHELL_POWERSHELL: POWERSHELL_COMPLETION_TEMPLATE,
+}
+
+type completionData struct {
+	Functions *FunctionNames
+}
+
+// GenerateCompletion prints the completion script of the griffin command for shell.
+func GenerateCompletion(shell string) error {
+	script, err := Completion(shell, nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(script)
+	return nil
+}
+
+// Completion returns the completion script of shell, which also completes the integration functions when given.
+func Completion(shell string, functions *FunctionNames) (string, error) {
+	completionTemplate, found := COMPLETION_TEMPLATES[shell]
+	if !found {
+		return "", fmt.Errorf("unsupported shell '%s' (supported shells: %s)", shell, strings.Join(SUPPORTED_SHELLS, ", "))
+	}
+
+	var script strings.Builder
+	if err := template.Must(template.New(shell).Parse(completionTemplate)).Execute(&script, completionData{Functions: functions}); err != nil {
+		return "", err
+	}
+	return script.String(), nil
+}
@@ -4,96 +4,251 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
 
 	"ronkitay.com/griffin/pkg/terminal"
 )
 
-func GenerateIntegration() {
+// MissingToolError is returned when a tool the integration relies on is not installed.
+type MissingToolError struct {
+	Tool string
+}
+
+func (err *MissingToolError) Error() string {
+	return fmt.Sprintf("tool '%s' not found in the PATH", err.Tool)
+}
+
+// Pickers the integration can use to choose between several results
+const (
+	PICKER_AUTO   = "auto"
+	PICKER_FZF    = "fzf"
+	PICKER_NATIVE = "native"
+)
+
+var SUPPORTED_PICKERS = []string{PICKER_AUTO, PICKER_FZF, PICKER_NATIVE}
+
+// Shells the integration can be generated for
+const (
+	SHELL_BASH       = "bash"
+	SHELL_ZSH        = "zsh"
+	SHELL_FISH       = "fish"
+	SHELL_NUSHELL    = "nushell"
+	SHELL_POWERSHELL = "powershell"
+)
+
+var SUPPORTED_SHELLS = []string{SHELL_BASH, SHELL_ZSH, SHELL_FISH, SHELL_NUSHELL, SHELL_POWERSHELL}
+
+// SHELL_EXECUTABLES maps the executables $SHELL may point at to the shells they run
+var SHELL_EXECUTABLES = map[string]string{
+	"bash":       SHELL_BASH,
+	"zsh":        SHELL_ZSH,
+	"fish":       SHELL_FISH,
+	"nu":         SHELL_NUSHELL,
+	"pwsh":       SHELL_POWERSHELL,
+	"powershell": SHELL_POWERSHELL,
+}
+
+var SCRIPT_TEMPLATES = map[string]string{
+	SHELL_BASH:       POSIX_SCRIPT_TEMPLATE,
+	SHELL_ZSH:        POSIX_SCRIPT_TEMPLATE,
+	SHELL_FISH:       FISH_SCRIPT_TEMPLATE,
+	SHELL_NUSHELL:    NUSHELL_SCRIPT_TEMPLATE,
+	SHELL_POWERSHELL: POWERSHELL_SCRIPT_TEMPLATE,
+}
+
+// FunctionNames are the names of the functions the integration defines
+type FunctionNames struct {
+	SwitchToRepo    string
+	OpenRepo        string
+	SwitchToProject string
+	OpenProject     string
+}
+
+func (names FunctionNames) All() []string {
+	return []string{names.SwitchToRepo, names.OpenRepo, names.SwitchToProject, names.OpenProject}
+}
+
+var DEFAULT_FUNCTION_NAMES = FunctionNames{SwitchToRepo: "r", OpenRepo: "or", SwitchToProject: "p", OpenProject: "op"}
+
+// RESERVED_NAME_REPLACEMENTS rename the default functions whose names are reserved words in a shell
+var RESERVED_NAME_REPLACEMENTS = map[string]map[string]string{
+	SHELL_FISH:    {"or": "ore"},
+	SHELL_NUSHELL: {"or": "ore"},
+}
+
+// DEFAULT_PREVIEW_COMMAND previews results in fzf. griffin pick has a built-in preview, used when none is configured.
+const DEFAULT_PREVIEW_COMMAND = "tree -L 2 -C {}"
 
-	dependencies := []string{"fzf", "tree"}
+// FUNCTION_NAME is what the function names must look like to be valid in all the supported shells
+var FUNCTION_NAME = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
 
-	for _, tool := range dependencies {
-		_, notInstalledError := toolIsInstalled(tool)
-		if notInstalledError != nil {
-			fmt.Printf("Tool '%s' not found in the PATH.\nInstall it using the following command:\nbrew install %s\n\n", tool, tool)
-			os.Exit(1)
+// Options control the functions the integration defines
+type Options struct {
+	// Picker chooses between several results - one of SUPPORTED_PICKERS
+	Picker string
+	// Functions are the names of the functions, where empty names keep their DEFAULT_FUNCTION_NAMES
+	Functions FunctionNames
+	// PreviewCommand previews the highlighted result, with {} standing for its path
+	PreviewCommand string
+	// AutoJump switches to the only result of a query, without picking it first
+	AutoJump bool
+}
+
+var DEFAULT_OPTIONS = Options{Picker: PICKER_AUTO, AutoJump: true}
+
+type scriptData struct {
+	Fzf         bool
+	ArchiveHint string
+	Functions   FunctionNames
+	// Preview is the quoted preview command, empty for the built-in preview of griffin pick
+	Preview string
+	// PickPreview passes Preview to griffin pick
+	PickPreview string
+	AutoJump    bool
+	Completion  string
+}
+
+// DetectShell returns the shell $SHELL points at
+func DetectShell() (string, error) {
+	shellPath := os.Getenv("SHELL")
+	if shell, found := SHELL_EXECUTABLES[strings.TrimSuffix(filepath.Base(shellPath), ".exe")]; found && shellPath != "" {
+		return shell, nil
+	}
+	return "", fmt.Errorf("cannot detect the shell from $SHELL (%q) - choose one with -shell (supported shells: %s)", shellPath, strings.Join(SUPPORTED_SHELLS, ", "))
+}
+
+// GenerateIntegration prints the integration script of shell, which defines functions to switch to (r, p) or open
+// (or, op) repositories and projects, and completes them along with the griffin command.
+func GenerateIntegration(shell string, options Options) error {
+	script, err := Integration(shell, options)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(script)
+	return nil
+}
+
+// Integration returns the integration script of shell. With the auto picker, fzf is used when it is installed (along
+// with tree, for the default preview), and the built-in `griffin pick` otherwise.
+func Integration(shell string, options Options) (string, error) {
+	scriptTemplate, found := SCRIPT_TEMPLATES[shell]
+	if !found {
+		return "", fmt.Errorf("unsupported shell '%s' (supported shells: %s)", shell, strings.Join(SUPPORTED_SHELLS, ", "))
+	}
+
+	picker := options.Picker
+	switch picker {
+	case PICKER_AUTO:
+		if missingTool(options.PreviewCommand) == "" {
+			picker = PICKER_FZF
+		} else {
+			picker = PICKER_NATIVE
+		}
+	case PICKER_FZF:
+		if tool := missingTool(options.PreviewCommand); tool != "" {
+			return "", &MissingToolError{Tool: tool}
 		}
+	case PICKER_NATIVE:
+	default:
+		return "", fmt.Errorf("unsupported picker '%s' (supported pickers: %s)", picker, strings.Join(SUPPORTED_PICKERS, ", "))
 	}
 
-	scriptTemplate := `
-	function r() {
-		TEMP_LIST_FILE=$(mktemp)
-	
-		griffin find-repo $* > "${TEMP_LIST_FILE}"
-	
-		if [[ "$(cat "${TEMP_LIST_FILE}" | wc -l)" -eq "1" ]]; 
-		then 
-			DIR_TO_SWITCH_TO=$(cat "${TEMP_LIST_FILE}")
-		else
-			DIR_TO_SWITCH_TO=$(cat "${TEMP_LIST_FILE}" | fzf +m --preview 'tree -L 2 -C {}')
-		fi
-		rm "${TEMP_LIST_FILE}"
-	
-		if [[ "${DIR_TO_SWITCH_TO}" = *.git ]]; 
-		then
-			cd $(dirname "${DIR_TO_SWITCH_TO}");
-			echo "%s%sTo access the repo, run the following command:%s"
-			echo ""
-			echo "unarchive $(basename ${DIR_TO_SWITCH_TO})"
-			echo ""
-		else
-			cd "${DIR_TO_SWITCH_TO}"
-		fi
+	names, err := functionNames(shell, options.Functions)
+	if err != nil {
+		return "", err
 	}
-	
-	function or() {
-		TEMP_LIST_FILE=$(mktemp)
-		
-		griffin find-repo --noarchive --nodir $* > "${TEMP_LIST_FILE}"
-		
-		PROJECT_DIR=$(cat "${TEMP_LIST_FILE}" | fzf +m --preview 'tree -L 2 -C {}')
-		
-		rm "${TEMP_LIST_FILE}"
-		
-		if [[ -n "${PROJECT_DIR}" ]]; then
-			griffin open-in-ide "${PROJECT_DIR}"
-		fi
+
+	completion, err := Completion(shell, &names)
+	if err != nil {
+		return "", err
 	}
 
-	function p() {
-		TEMP_LIST_FILE=$(mktemp)
-
-		griffin find-project $* > "${TEMP_LIST_FILE}"
-	
-		if [[ "$(cat "${TEMP_LIST_FILE}" | wc -l)" -eq "1" ]]; 
-		then 
-			DIR_TO_SWITCH_TO=$(cat "${TEMP_LIST_FILE}")
-		else
-			DIR_TO_SWITCH_TO=$(cat "${TEMP_LIST_FILE}" | fzf +m --preview 'tree -L 2 -C {}')
-		fi
-		rm "${TEMP_LIST_FILE}"
-	
-		cd "${DIR_TO_SWITCH_TO}"
+	previewCommand := options.PreviewCommand
+	if previewCommand == "" && picker == PICKER_FZF {
+		previewCommand = DEFAULT_PREVIEW_COMMAND
+	}
+	var preview, pickPreview string
+	if previewCommand != "" {
+		preview = quote(shell, previewCommand)
+		pickPreview = " -preview " + preview
 	}
 
-	function op() {
-		TEMP_LIST_FILE=$(mktemp)
-		
-		griffin find-project $* > "${TEMP_LIST_FILE}"
-		
-		PROJECT_DIR=$(cat "${TEMP_LIST_FILE}" | fzf +m --preview 'tree -L 2 -C {}')
-		
-		rm "${TEMP_LIST_FILE}"
-		
-		if [[ -n "${PROJECT_DIR}" ]]; then
-			griffin open-in-ide "${PROJECT_DIR}"
-		fi
+	data := scriptData{
+		Fzf:         picker == PICKER_FZF,
+		ArchiveHint: terminal.BOLD_COLOR + terminal.GREEN_COLOR + "To access the repo, run the following command:" + terminal.RESET_COLORS,
+		Functions:   names,
+		Preview:     preview,
+		PickPreview: pickPreview,
+		AutoJump:    options.AutoJump,
+		Completion:  completion,
 	}
-	`
 
-	script := fmt.Sprintf(scriptTemplate, terminal.BOLD_COLOR, terminal.GREEN_COLOR, terminal.RESET_COLORS)
+	var script strings.Builder
+	if err := template.Must(template.New(shell).Parse(scriptTemplate)).Execute(&script, data); err != nil {
+		return "", err
+	}
+	return script.String(), nil
+}
 
-	fmt.Println(script)
+// functionNames returns the configured function names, using the defaults for the ones that are not configured
+func functionNames(shell string, configured FunctionNames) (FunctionNames, error) {
+	names := DEFAULT_FUNCTION_NAMES
+	replacements := RESERVED_NAME_REPLACEMENTS[shell]
+	seen := map[string]bool{}
+	for _, name := range []struct{ value, configured *string }{
+		{&names.SwitchToRepo, &configured.SwitchToRepo},
+		{&names.OpenRepo, &configured.OpenRepo},
+		{&names.SwitchToProject, &configured.SwitchToProject},
+		{&names.OpenProject, &configured.OpenProject},
+	} {
+		if *name.configured != "" {
+			*name.value = *name.configured
+		} else if replacement, reserved := replacements[*name.value]; reserved {
+			*name.value = replacement
+		}
+
+		if !FUNCTION_NAME.MatchString(*name.value) {
+			return FunctionNames{}, fmt.Errorf("invalid function name '%s' - use letters, digits, '_' and '-'", *name.value)
+		}
+		if seen[*name.value] {
+			return FunctionNames{}, fmt.Errorf("function name '%s' is used twice", *name.value)
+		}
+		seen[*name.value] = true
+	}
+	return names, nil
+}
+
+// quote quotes text as a single argument of shell, where it is not expanded
+func quote(shell string, text string) string {
+	switch shell {
+	case SHELL_FISH:
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(text) + "'"
+	case SHELL_NUSHELL:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+	case SHELL_POWERSHELL:
+		return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+	default:
+		return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+	}
+}
+
+// missingTool returns the first tool the fzf integration needs that is not installed. tree is only needed by the
+// default preview command.
+func missingTool(previewCommand string) string {
+	tools := []string{"fzf"}
+	if previewCommand == "" {
+		tools = append(tools, "tree")
+	}
+	for _, tool := range tools {
+		if _, notInstalledError := toolIsInstalled(tool); notInstalledError != nil {
+			return tool
+		}
+	}
+	return ""
 }
 
 func toolIsInstalled(tool string) (bool, error) {
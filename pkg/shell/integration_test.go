@@ -0,0 +1,229 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeTools puts empty fzf and tree executables first in the PATH, so the fzf integration can be generated
+func fakeTools(t *testing.T) {
+	binDir := t.TempDir()
+	for _, tool := range []string{"fzf", "tree"} {
+		if err := os.WriteFile(filepath.Join(binDir, tool), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func withPicker(picker string) Options {
+	options := DEFAULT_OPTIONS
+	options.Picker = picker
+	return options
+}
+
+// SYNTAX_CHECKS parse a script without running it, for the shells that support it
+var SYNTAX_CHECKS = map[string][]string{
+	SHELL_BASH: {"bash", "-n"},
+	SHELL_ZSH:  {"zsh", "-n"},
+	SHELL_FISH: {"fish", "--no-execute"},
+}
+
+func TestIntegration_ValidSyntax(t *testing.T) {
+	fakeTools(t)
+
+	for shell, check := range SYNTAX_CHECKS {
+		if _, err := exec.LookPath(check[0]); err != nil {
+			t.Logf("Skipping %s, which is not installed", shell)
+			continue
+		}
+
+		for _, picker := range []string{PICKER_FZF, PICKER_NATIVE} {
+			script, err := Integration(shell, withPicker(picker))
+			if err != nil {
+				t.Fatalf("Integration(%s, %s) failed: %v", shell, picker, err)
+			}
+
+			scriptFile := filepath.Join(t.TempDir(), "integration")
+			os.WriteFile(scriptFile, []byte(script), 0644)
+			if output, err := exec.Command(check[0], append(check[1:], scriptFile)...).CombinedOutput(); err != nil {
+				t.Errorf("Invalid %s script with the %s picker: %v\n%s\n%s", shell, picker, err, output, script)
+			}
+		}
+	}
+}
+
+func TestIntegration_Pickers(t *testing.T) {
+	fakeTools(t)
+
+	for _, shell := range SUPPORTED_SHELLS {
+		fzfScript, err := Integration(shell, withPicker(PICKER_AUTO))
+		if err != nil {
+			t.Fatalf("Integration(%s) failed: %v", shell, err)
+		}
+		if !strings.Contains(fzfScript, "| fzf +m") || strings.Contains(fzfScript, "griffin pick") {
+			t.Errorf("Expected the %s script to use fzf when it is installed:\n%s", shell, fzfScript)
+		}
+
+		nativeScript, err := Integration(shell, withPicker(PICKER_NATIVE))
+		if err != nil {
+			t.Fatalf("Integration(%s) failed: %v", shell, err)
+		}
+		if strings.Contains(nativeScript, "fzf") || !strings.Contains(nativeScript, "griffin pick -projects -open-in-ide") {
+			t.Errorf("Expected the %s script to use griffin pick:\n%s", shell, nativeScript)
+		}
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if _, err := Integration(SHELL_BASH, withPicker(PICKER_FZF)); err == nil {
+		t.Errorf("Expected the fzf picker to fail without fzf")
+	}
+}
+
+func TestIntegration_ReservedNames(t *testing.T) {
+	script, err := Integration(SHELL_FISH, withPicker(PICKER_NATIVE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(script, "function or ") || !strings.Contains(script, "function ore ") {
+		t.Errorf("Expected or to be renamed in fish, where it is a reserved word:\n%s", script)
+	}
+
+	if _, err := Integration("tcsh", withPicker(PICKER_NATIVE)); err == nil {
+		t.Errorf("Expected an unsupported shell to fail")
+	}
+}
+
+func TestDetectShell(t *testing.T) {
+	for shellPath, expected := range map[string]string{
+		"/bin/zsh":             SHELL_ZSH,
+		"/usr/local/bin/fish":  SHELL_FISH,
+		"/opt/homebrew/bin/nu": SHELL_NUSHELL,
+		"/usr/bin/pwsh":        SHELL_POWERSHELL,
+	} {
+		t.Setenv("SHELL", shellPath)
+		if shell, err := DetectShell(); err != nil || shell != expected {
+			t.Errorf("Expected %s for %s, got %q (%v)", expected, shellPath, shell, err)
+		}
+	}
+
+	t.Setenv("SHELL", "/bin/tcsh")
+	if _, err := DetectShell(); err == nil {
+		t.Errorf("Expected an error for an unsupported shell")
+	}
+}
+
+func TestCompletion_ValidSyntax(t *testing.T) {
+	for shell, check := range SYNTAX_CHECKS {
+		if _, err := exec.LookPath(check[0]); err != nil {
+			t.Logf("Skipping %s, which is not installed", shell)
+			continue
+		}
+
+		script, err := Completion(shell, nil)
+		if err != nil {
+			t.Fatalf("Completion(%s) failed: %v", shell, err)
+		}
+
+		scriptFile := filepath.Join(t.TempDir(), "completion")
+		os.WriteFile(scriptFile, []byte(script), 0644)
+		if output, err := exec.Command(check[0], append(check[1:], scriptFile)...).CombinedOutput(); err != nil {
+			t.Errorf("Invalid %s completion: %v\n%s\n%s", shell, err, output, script)
+		}
+	}
+}
+
+func TestCompletion_Functions(t *testing.T) {
+	for _, shell := range SUPPORTED_SHELLS {
+		griffinOnly, err := Completion(shell, nil)
+		if err != nil {
+			t.Fatalf("Completion(%s) failed: %v", shell, err)
+		}
+		if strings.Contains(griffinOnly, "find-repo") {
+			t.Errorf("Expected the %s completion to leave out the integration functions:\n%s", shell, griffinOnly)
+		}
+
+		integration, err := Integration(shell, withPicker(PICKER_NATIVE))
+		if err != nil {
+			t.Fatalf("Integration(%s) failed: %v", shell, err)
+		}
+		names, _ := functionNames(shell, FunctionNames{})
+		withFunctions, _ := Completion(shell, &names)
+		if !strings.Contains(withFunctions, "find-repo") || !strings.Contains(integration, withFunctions) {
+			t.Errorf("Expected the %s integration to complete its functions from the indexes:\n%s", shell, integration)
+		}
+	}
+
+	if _, err := Completion("tcsh", nil); err == nil {
+		t.Errorf("Expected an unsupported shell to fail")
+	}
+}
+
+func TestIntegration_Options(t *testing.T) {
+	fakeTools(t)
+
+	options := Options{
+		Picker:         PICKER_FZF,
+		Functions:      FunctionNames{SwitchToRepo: "repo", OpenProject: "open_project"},
+		PreviewCommand: `ls -la {} | grep -v '^total'`,
+	}
+	for shell, check := range SYNTAX_CHECKS {
+		for _, picker := range []string{PICKER_FZF, PICKER_NATIVE} {
+			options.Picker = picker
+			script, err := Integration(shell, options)
+			if err != nil {
+				t.Fatalf("Integration(%s, %s) failed: %v", shell, picker, err)
+			}
+			if !strings.Contains(script, "function repo") || !strings.Contains(script, "function open_project") || strings.Contains(script, "function r(") {
+				t.Errorf("Expected the %s script to use the configured function names:\n%s", shell, script)
+			}
+			if strings.Contains(script, "tree") || strings.Contains(script, "-select-1") || strings.Contains(script, "fzf +m -1") {
+				t.Errorf("Expected the %s script to use the configured preview without auto-jumping:\n%s", shell, script)
+			}
+
+			if _, err := exec.LookPath(check[0]); err != nil {
+				continue
+			}
+			scriptFile := filepath.Join(t.TempDir(), "integration")
+			os.WriteFile(scriptFile, []byte(script), 0644)
+			if output, err := exec.Command(check[0], append(check[1:], scriptFile)...).CombinedOutput(); err != nil {
+				t.Errorf("Invalid %s script with the %s picker: %v\n%s\n%s", shell, picker, err, output, script)
+			}
+		}
+	}
+
+	for _, functions := range []FunctionNames{{SwitchToRepo: "r;rm"}, {OpenRepo: "p"}} {
+		if _, err := Integration(SHELL_BASH, Options{Picker: PICKER_NATIVE, Functions: functions}); err == nil {
+			t.Errorf("Expected the function names %+v to be rejected", functions)
+		}
+	}
+}
+
+func TestIntegration_PreviewWithoutTree(t *testing.T) {
+	binDir := t.TempDir()
+	os.WriteFile(filepath.Join(binDir, "fzf"), []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", binDir)
+
+	if _, err := Integration(SHELL_BASH, withPicker(PICKER_FZF)); err == nil {
+		t.Errorf("Expected the default preview to need tree")
+	}
+	if _, err := Integration(SHELL_BASH, Options{Picker: PICKER_FZF, PreviewCommand: "ls {}"}); err != nil {
+		t.Errorf("Expected a configured preview not to need tree: %v", err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for shell, expected := range map[string]string{
+		SHELL_BASH:       `'a '\''b'\'' \c'`,
+		SHELL_FISH:       `'a \'b\' \\c'`,
+		SHELL_NUSHELL:    `"a 'b' \\c"`,
+		SHELL_POWERSHELL: `'a ''b'' \c'`,
+	} {
+		if quoted := quote(shell, `a 'b' \c`); quoted != expected {
+			t.Errorf("Expected %s to quote as %s, got %s", shell, expected, quoted)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package shell
+
+// The integration scripts of each shell, as text/template templates executed with a scriptData, built from Options.
+// With fzf, results are listed with find-repo/find-project and fzf chooses between them. Otherwise `griffin pick`
+// chooses, records the selection, and opens IDEs and web pages by itself.
+
+// POSIX_SCRIPT_TEMPLATE is used by both bash and zsh
+const POSIX_SCRIPT_TEMPLATE = `
+	function {{.Functions.SwitchToRepo}}() {
+{{- if .Fzf}}
+		TEMP_LIST_FILE=$(mktemp)
+
+		griffin find-repo $* > "${TEMP_LIST_FILE}"
+
+{{- if .AutoJump}}
+		if [[ "$(cat "${TEMP_LIST_FILE}" | wc -l)" -eq "1" ]];
+		then
+			DIR_TO_SWITCH_TO=$(cat "${TEMP_LIST_FILE}")
+		else
+			DIR_TO_SWITCH_TO=$(cat "${TEMP_LIST_FILE}" | fzf +m --preview {{.Preview}})
+		fi
+{{- else}}
+		DIR_TO_SWITCH_TO=$(cat "${TEMP_LIST_FILE}" | fzf +m --preview {{.Preview}})
+{{- end}}
+		rm "${TEMP_LIST_FILE}"
+
+		if [[ -n "${DIR_TO_SWITCH_TO}" ]]; then
+			griffin record "${DIR_TO_SWITCH_TO}" > /dev/null 2>&1
+		fi
+{{- else}}
+		DIR_TO_SWITCH_TO=$(griffin pick{{.PickPreview}}{{if .AutoJump}} -select-1{{end}} $*)
+{{- end}}
+
+		if [[ "${DIR_TO_SWITCH_TO}" = *.git ]];
+		then
+			cd $(dirname "${DIR_TO_SWITCH_TO}");
+			echo "{{.ArchiveHint}}"
+			echo ""
+			echo "unarchive $(basename ${DIR_TO_SWITCH_TO})"
+			echo ""
+		elif [[ -n "${DIR_TO_SWITCH_TO}" ]]; then
+			cd "${DIR_TO_SWITCH_TO}"
+		fi
+	}
+
+	function {{.Functions.OpenRepo}}() {
+{{- if .Fzf}}
+		TEMP_LIST_FILE=$(mktemp)
+
+		griffin find-repo --noarchive --nodir $* > "${TEMP_LIST_FILE}"
+
+		PROJECT_DIR=$(cat "${TEMP_LIST_FILE}" | fzf +m --preview {{.Preview}})
+
+		rm "${TEMP_LIST_FILE}"
+
+		if [[ -n "${PROJECT_DIR}" ]]; then
+			griffin record "${PROJECT_DIR}" > /dev/null 2>&1
+			griffin open-in-ide "${PROJECT_DIR}"
+		fi
+{{- else}}
+		griffin pick{{.PickPreview}} -open-in-ide -noarchive -nodir $*
+{{- end}}
+	}
+
+	function {{.Functions.SwitchToProject}}() {
+{{- if .Fzf}}
+		TEMP_LIST_FILE=$(mktemp)
+
+		griffin find-project $* > "${TEMP_LIST_FILE}"
+
+{{- if .AutoJump}}
+		if [[ "$(cat "${TEMP_LIST_FILE}" | wc -l)" -eq "1" ]];
+		then
+			DIR_TO_SWITCH_TO=$(cat "${TEMP_LIST_FILE}")
+		else
+			DIR_TO_SWITCH_TO=$(cat "${TEMP_LIST_FILE}" | fzf +m --preview {{.Preview}})
+		fi
+{{- else}}
+		DIR_TO_SWITCH_TO=$(cat "${TEMP_LIST_FILE}" | fzf +m --preview {{.Preview}})
+{{- end}}
+		rm "${TEMP_LIST_FILE}"
+
+		if [[ -n "${DIR_TO_SWITCH_TO}" ]]; then
+			griffin record "${DIR_TO_SWITCH_TO}" > /dev/null 2>&1
+		fi
+{{- else}}
+		DIR_TO_SWITCH_TO=$(griffin pick -projects{{.PickPreview}}{{if .AutoJump}} -select-1{{end}} $*)
+{{- end}}
+
+		if [[ -n "${DIR_TO_SWITCH_TO}" ]]; then
+			cd "${DIR_TO_SWITCH_TO}"
+		fi
+	}
+
+	function {{.Functions.OpenProject}}() {
+{{- if .Fzf}}
+		TEMP_LIST_FILE=$(mktemp)
+
+		griffin find-project $* > "${TEMP_LIST_FILE}"
+
+		PROJECT_DIR=$(cat "${TEMP_LIST_FILE}" | fzf +m --preview {{.Preview}})
+
+		rm "${TEMP_LIST_FILE}"
+
+		if [[ -n "${PROJECT_DIR}" ]]; then
+			griffin record "${PROJECT_DIR}" > /dev/null 2>&1
+			griffin open-in-ide "${PROJECT_DIR}"
+		fi
+{{- else}}
+		griffin pick -projects{{.PickPreview}} -open-in-ide $*
+{{- end}}
+	}
+{{.Completion}}`
+
+const FISH_SCRIPT_TEMPLATE = `
+function {{.Functions.SwitchToRepo}} --description 'Switch to a repository'
+{{- if .Fzf}}
+    set -l dir (griffin find-repo $argv | fzf +m{{if .AutoJump}} -1{{end}} --preview {{.Preview}})
+    test -n "$dir"; or return
+    griffin record $dir >/dev/null 2>&1
+{{- else}}
+    set -l dir (griffin pick{{.PickPreview}}{{if .AutoJump}} -select-1{{end}} $argv)
+    test -n "$dir"; or return
+{{- end}}
+
+    if string match -q '*.git' -- $dir
+        cd (dirname $dir)
+        echo "{{.ArchiveHint}}"
+        echo ""
+        echo "unarchive "(basename $dir)
+        echo ""
+    else
+        cd $dir
+    end
+end
+
+function {{.Functions.OpenRepo}} --description 'Open a repository in its IDE'
+{{- if .Fzf}}
+    set -l dir (griffin find-repo --noarchive --nodir $argv | fzf +m --preview {{.Preview}})
+    test -n "$dir"; or return
+    griffin record $dir >/dev/null 2>&1
+    griffin open-in-ide $dir
+{{- else}}
+    griffin pick{{.PickPreview}} -open-in-ide -noarchive -nodir $argv
+{{- end}}
+end
+
+function {{.Functions.SwitchToProject}} --description 'Switch to a project'
+{{- if .Fzf}}
+    set -l dir (griffin find-project $argv | fzf +m{{if .AutoJump}} -1{{end}} --preview {{.Preview}})
+    test -n "$dir"; or return
+    griffin record $dir >/dev/null 2>&1
+{{- else}}
+    set -l dir (griffin pick -projects{{.PickPreview}}{{if .AutoJump}} -select-1{{end}} $argv)
+    test -n "$dir"; or return
+{{- end}}
+    cd $dir
+end
+
+function {{.Functions.OpenProject}} --description 'Open a project in its IDE'
+{{- if .Fzf}}
+    set -l dir (griffin find-project $argv | fzf +m --preview {{.Preview}})
+    test -n "$dir"; or return
+    griffin record $dir >/dev/null 2>&1
+    griffin open-in-ide $dir
+{{- else}}
+    griffin pick -projects{{.PickPreview}} -open-in-ide $argv
+{{- end}}
+end
+{{.Completion}}`
+
+// NUSHELL_SCRIPT_TEMPLATE runs griffin and fzf with `do -i`, so cancelling a selection is not reported as an error.
+// The completion comes first, since the functions' signatures refer to its completers.
+const NUSHELL_SCRIPT_TEMPLATE = `{{.Completion}}
+# Switch to a repository
+def --env {{.Functions.SwitchToRepo}} [...query: string@"nu-complete griffin repos"] {
+{{- if .Fzf}}
+    let dir = (do -i { griffin find-repo ...$query | fzf +m{{if .AutoJump}} -1{{end}} --preview {{.Preview}} } | str trim)
+    if ($dir | is-empty) { return }
+    do -i { griffin record $dir } | ignore
+{{- else}}
+    let dir = (do -i { griffin pick{{.PickPreview}}{{if .AutoJump}} -select-1{{end}} ...$query } | str trim)
+    if ($dir | is-empty) { return }
+{{- end}}
+
+    if ($dir | str ends-with '.git') {
+        cd ($dir | path dirname)
+        print "{{.ArchiveHint}}"
+        print ""
+        print $"unarchive ($dir | path basename)"
+        print ""
+    } else {
+        cd $dir
+    }
+}
+
+# Open a repository in its IDE
+def {{.Functions.OpenRepo}} [...query: string@"nu-complete griffin repos"] {
+{{- if .Fzf}}
+    let dir = (do -i { griffin find-repo --noarchive --nodir ...$query | fzf +m --preview {{.Preview}} } | str trim)
+    if ($dir | is-empty) { return }
+    do -i { griffin record $dir } | ignore
+    griffin open-in-ide $dir
+{{- else}}
+    do -i { griffin pick{{.PickPreview}} -open-in-ide -noarchive -nodir ...$query }
+{{- end}}
+}
+
+# Switch to a project
+def --env {{.Functions.SwitchToProject}} [...query: string@"nu-complete griffin projects"] {
+{{- if .Fzf}}
+    let dir = (do -i { griffin find-project ...$query | fzf +m{{if .AutoJump}} -1{{end}} --preview {{.Preview}} } | str trim)
+    if ($dir | is-empty) { return }
+    do -i { griffin record $dir } | ignore
+{{- else}}
+    let dir = (do -i { griffin pick -projects{{.PickPreview}}{{if .AutoJump}} -select-1{{end}} ...$query } | str trim)
+    if ($dir | is-empty) { return }
+{{- end}}
+    cd $dir
+}
+
+# Open a project in its IDE
+def {{.Functions.OpenProject}} [...query: string@"nu-complete griffin projects"] {
+{{- if .Fzf}}
+    let dir = (do -i { griffin find-project ...$query | fzf +m --preview {{.Preview}} } | str trim)
+    if ($dir | is-empty) { return }
+    do -i { griffin record $dir } | ignore
+    griffin open-in-ide $dir
+{{- else}}
+    do -i { griffin pick -projects{{.PickPreview}} -open-in-ide ...$query }
+{{- end}}
+}
+`
+
+// POWERSHELL_SCRIPT_TEMPLATE removes built-in aliases first (such as r, for Invoke-History), since aliases take
+// precedence over functions. The functions name their first argument Query only so it can be completed - it is passed
+// after the other arguments, which keep flags such as -regex in $args.
+const POWERSHELL_SCRIPT_TEMPLATE = `
+{{- range .Functions.All}}
+Remove-Item -Path Alias:{{.}} -Force -ErrorAction SilentlyContinue
+{{- end}}
+
+# Switch to a repository
+function {{.Functions.SwitchToRepo}} {
+    param([string]$Query)
+{{- if .Fzf}}
+    $dir = griffin find-repo @args @($Query | Where-Object { $_ }) | fzf +m{{if .AutoJump}} -1{{end}} --preview {{.Preview}}
+    if (-not $dir) { return }
+    griffin record $dir *> $null
+{{- else}}
+    $dir = griffin pick{{.PickPreview}}{{if .AutoJump}} -select-1{{end}} @args @($Query | Where-Object { $_ })
+    if (-not $dir) { return }
+{{- end}}
+
+    if ($dir.EndsWith('.git')) {
+        Set-Location (Split-Path -Parent $dir)
+        Write-Host "{{.ArchiveHint}}"
+        Write-Host ""
+        Write-Host "unarchive $(Split-Path -Leaf $dir)"
+        Write-Host ""
+    } else {
+        Set-Location $dir
+    }
+}
+
+# Open a repository in its IDE
+function {{.Functions.OpenRepo}} {
+    param([string]$Query)
+{{- if .Fzf}}
+    $dir = griffin find-repo --noarchive --nodir @args @($Query | Where-Object { $_ }) | fzf +m --preview {{.Preview}}
+    if (-not $dir) { return }
+    griffin record $dir *> $null
+    griffin open-in-ide $dir
+{{- else}}
+    griffin pick{{.PickPreview}} -open-in-ide -noarchive -nodir @args @($Query | Where-Object { $_ })
+{{- end}}
+}
+
+# Switch to a project
+function {{.Functions.SwitchToProject}} {
+    param([string]$Query)
+{{- if .Fzf}}
+    $dir = griffin find-project @args @($Query | Where-Object { $_ }) | fzf +m{{if .AutoJump}} -1{{end}} --preview {{.Preview}}
+    if (-not $dir) { return }
+    griffin record $dir *> $null
+{{- else}}
+    $dir = griffin pick -projects{{.PickPreview}}{{if .AutoJump}} -select-1{{end}} @args @($Query | Where-Object { $_ })
+    if (-not $dir) { return }
+{{- end}}
+    Set-Location $dir
+}
+
+# Open a project in its IDE
+function {{.Functions.OpenProject}} {
+    param([string]$Query)
+{{- if .Fzf}}
+    $dir = griffin find-project @args @($Query | Where-Object { $_ }) | fzf +m --preview {{.Preview}}
+    if (-not $dir) { return }
+    griffin record $dir *> $null
+    griffin open-in-ide $dir
+{{- else}}
+    griffin pick -projects{{.PickPreview}} -open-in-ide @args @($Query | Where-Object { $_ })
+{{- end}}
+}
+{{.Completion}}`
@@ -3,8 +3,26 @@ package terminal
 const (
 	RESET_COLORS = "\033[0m"
 	BOLD_COLOR   = "\033[1m"
+	DIM_COLOR    = "\033[2m"
 
-	RED_COLOR   = "\033[31m"
-	GREEN_COLOR = "\033[32m"
-	WHITE_COLOR = "\033[37m"
-)
\ No newline at end of file
+	RED_COLOR     = "\033[31m"
+	GREEN_COLOR   = "\033[32m"
+	YELLOW_COLOR  = "\033[33m"
+	BLUE_COLOR    = "\033[34m"
+	MAGENTA_COLOR = "\033[35m"
+	CYAN_COLOR    = "\033[36m"
+	WHITE_COLOR   = "\033[37m"
+)
+
+// COLORS_BY_NAME maps color names (as used in output templates) to their escape codes
+var COLORS_BY_NAME = map[string]string{
+	"bold":    BOLD_COLOR,
+	"dim":     DIM_COLOR,
+	"red":     RED_COLOR,
+	"green":   GREEN_COLOR,
+	"yellow":  YELLOW_COLOR,
+	"blue":    BLUE_COLOR,
+	"magenta": MAGENTA_COLOR,
+	"cyan":    CYAN_COLOR,
+	"white":   WHITE_COLOR,
+}
@@ -0,0 +1,109 @@
+package watcher
+
+import (
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ONLYDIR
+
+type inotifyWatcher struct {
+	fd           int
+	lock         sync.Mutex
+	watchedPaths map[int32]string
+	eventsChan   chan fsEvent
+	errorsChan   chan error
+}
+
+func newFSWatcher() (fsWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher := &inotifyWatcher{
+		fd:           fd,
+		watchedPaths: map[int32]string{},
+		eventsChan:   make(chan fsEvent, 1024),
+		errorsChan:   make(chan error, 1),
+	}
+	go watcher.readEvents()
+
+	return watcher, nil
+}
+
+func (watcher *inotifyWatcher) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(watcher.fd, dir, inotifyMask)
+	if err != nil {
+		return err
+	}
+
+	watcher.lock.Lock()
+	watcher.watchedPaths[int32(wd)] = dir
+	watcher.lock.Unlock()
+	return nil
+}
+
+func (watcher *inotifyWatcher) events() <-chan fsEvent {
+	return watcher.eventsChan
+}
+
+func (watcher *inotifyWatcher) errors() <-chan error {
+	return watcher.errorsChan
+}
+
+func (watcher *inotifyWatcher) close() error {
+	return syscall.Close(watcher.fd)
+}
+
+func (watcher *inotifyWatcher) readEvents() {
+	buffer := make([]byte, 64*1024)
+	for {
+		n, err := syscall.Read(watcher.fd, buffer)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			watcher.errorsChan <- err
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameBytes := buffer[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			watcher.lock.Lock()
+			dir, known := watcher.watchedPaths[event.Wd]
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(watcher.watchedPaths, event.Wd)
+			}
+			watcher.lock.Unlock()
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				watcher.eventsChan <- fsEvent{overflow: true}
+				continue
+			}
+			if !known || event.Len == 0 {
+				continue
+			}
+
+			watcher.eventsChan <- fsEvent{
+				path:  filepath.Join(dir, trimNulls(nameBytes)),
+				isDir: event.Mask&syscall.IN_ISDIR != 0,
+			}
+		}
+	}
+}
+
+// trimNulls strips the null padding inotify adds after file names.
+func trimNulls(name []byte) string {
+	for i, b := range name {
+		if b == 0 {
+			return string(name[:i])
+		}
+	}
+	return string(name)
+}
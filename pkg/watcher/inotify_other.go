@@ -0,0 +1,12 @@
+//go:build !linux
+
+package watcher
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func newFSWatcher() (fsWatcher, error) {
+	return nil, fmt.Errorf("watching is not supported on %s", runtime.GOOS)
+}
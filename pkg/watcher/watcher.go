@@ -0,0 +1,237 @@
+package watcher
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	config "ronkitay.com/griffin/pkg/configuration"
+	"ronkitay.com/griffin/pkg/language"
+	projectIndex "ronkitay.com/griffin/pkg/projectindex"
+	repoIndex "ronkitay.com/griffin/pkg/repoindex"
+)
+
+// How long to wait for the file system to settle before updating the indexes
+const debounceInterval = 500 * time.Millisecond
+
+// fsEvent is a directory entry that was created or removed (including moves in and out).
+// An overflow event means some events were lost.
+type fsEvent struct {
+	path     string
+	isDir    bool
+	overflow bool
+}
+
+// fsWatcher is implemented per OS - see inotify_linux.go.
+type fsWatcher interface {
+	add(dir string) error
+	events() <-chan fsEvent
+	errors() <-chan error
+	close() error
+}
+
+// Watcher keeps repo.list and project.list up to date while repositories and projects come and go.
+type Watcher struct {
+	configuration config.Configuration
+	detector      *language.Detector
+	jobs          int
+	fs            fsWatcher
+	repoDirs      map[string]struct{}
+	watchHint     bool
+
+	repoIndexStale bool
+	changedRepos   map[string]struct{}
+}
+
+// Watch brings both indexes up to date and then keeps updating them until the process is stopped.
+func Watch(configuration config.Configuration, jobs int) error {
+	roots, err := configuration.RepoRoots()
+	if err != nil {
+		return fmt.Errorf("error getting repository roots: %v", err)
+	}
+
+	detector, err := configuration.LanguageDetector()
+	if err != nil {
+		return err
+	}
+
+	fsWatcher, err := newFSWatcher()
+	if err != nil {
+		return fmt.Errorf("error starting file system watcher: %v", err)
+	}
+	defer fsWatcher.close()
+
+	watcher := &Watcher{configuration: configuration, detector: detector, jobs: jobs, fs: fsWatcher, repoDirs: map[string]struct{}{}, changedRepos: map[string]struct{}{}}
+	for _, root := range roots {
+		watcher.watchTree(root)
+	}
+
+	fmt.Printf("Watching %d repository roots\n", len(roots))
+	watcher.repoIndexStale = true
+	if err := watcher.flush(); err != nil {
+		return err
+	}
+
+	return watcher.run()
+}
+
+func (watcher *Watcher) run() error {
+	timer := time.NewTimer(debounceInterval)
+	timer.Stop()
+
+	for {
+		select {
+		case event := <-watcher.fs.events():
+			if watcher.handle(event) {
+				timer.Reset(debounceInterval)
+			}
+		case err := <-watcher.fs.errors():
+			return err
+		case <-timer.C:
+			if err := watcher.flush(); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+}
+
+// handle records what a file system event invalidates and reports whether an index update is needed.
+func (watcher *Watcher) handle(event fsEvent) bool {
+	if event.overflow {
+		watcher.repoIndexStale = true
+		for repoDir := range watcher.repoDirs {
+			watcher.changedRepos[repoDir] = struct{}{}
+		}
+		return true
+	}
+
+	dir, name := filepath.Split(event.path)
+	dir = filepath.Clean(dir)
+
+	switch {
+	case name == ".git":
+		// A repository was created or removed
+		if _, err := os.Stat(event.path); err == nil {
+			watcher.repoDirs[dir] = struct{}{}
+		} else {
+			delete(watcher.repoDirs, dir)
+		}
+		watcher.repoIndexStale = true
+		watcher.changedRepos[dir] = struct{}{}
+		return true
+	case strings.HasSuffix(name, ".git") && isArchive(event.path, event.isDir):
+		// An archive was created or removed
+		watcher.repoIndexStale = true
+		return true
+	}
+
+	repoDir, insideRepo := watcher.owningRepo(dir)
+
+	if event.isDir && projectIndex.DirCanBeSkipped(event.path) {
+		return false
+	}
+
+	if event.isDir {
+		if _, err := os.Stat(event.path); err == nil {
+			watcher.watchTree(event.path)
+		} else {
+			watcher.forgetTree(event.path)
+		}
+		if insideRepo {
+			watcher.changedRepos[repoDir] = struct{}{}
+		} else {
+			watcher.repoIndexStale = true
+		}
+		return true
+	}
+
+	if insideRepo && watcher.detector.IsMarker(name) {
+		watcher.changedRepos[repoDir] = struct{}{}
+		return true
+	}
+
+	return false
+}
+
+// isArchive tells whether a path named *.git is an archived repository: a file, as the repo index has them, or a bare
+// repository. Other directories named *.git are watched like any directory.
+func isArchive(path string, isDir bool) bool {
+	if !isDir {
+		return true
+	}
+	_, headErr := os.Stat(filepath.Join(path, "HEAD"))
+	_, objectsErr := os.Stat(filepath.Join(path, "objects"))
+	return headErr == nil && objectsErr == nil
+}
+
+func (watcher *Watcher) owningRepo(path string) (string, bool) {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, found := watcher.repoDirs[dir]; found {
+			return dir, true
+		}
+		if dir == filepath.Dir(dir) {
+			return "", false
+		}
+	}
+}
+
+// flush applies everything recorded since the last flush to the indexes.
+func (watcher *Watcher) flush() error {
+	if watcher.repoIndexStale {
+		if err := repoIndex.BuildRepoIndex(watcher.configuration, watcher.jobs, true); err != nil {
+			return fmt.Errorf("error updating repo index: %w", err)
+		}
+		fmt.Println("Repository index updated")
+	}
+
+	if watcher.repoIndexStale || len(watcher.changedRepos) > 0 {
+		if err := projectIndex.RefreshProjectIndex(watcher.configuration, watcher.changedRepos); err != nil {
+			return fmt.Errorf("error updating project index: %w", err)
+		}
+		fmt.Println("Project index updated")
+	}
+
+	watcher.repoIndexStale = false
+	watcher.changedRepos = map[string]struct{}{}
+	return nil
+}
+
+// watchTree watches dir and all directories below it, except for git internals and
+// directories that never contain projects.
+func (watcher *Watcher) watchTree(dir string) {
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if path != dir && projectIndex.DirCanBeSkipped(path) {
+			return filepath.SkipDir
+		}
+
+		if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+			watcher.repoDirs[path] = struct{}{}
+		}
+
+		if err := watcher.fs.add(path); err != nil {
+			if !watcher.watchHint {
+				watcher.watchHint = true
+				fmt.Printf("Error watching %s: %v\n", path, err)
+				fmt.Println("You may need to raise fs.inotify.max_user_watches (sysctl -w fs.inotify.max_user_watches=524288)")
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// forgetTree drops the repositories known under a removed directory.
+// The watches themselves are removed by the OS.
+func (watcher *Watcher) forgetTree(dir string) {
+	for repoDir := range watcher.repoDirs {
+		if repoDir == dir || strings.HasPrefix(repoDir, dir+string(filepath.Separator)) {
+			delete(watcher.repoDirs, repoDir)
+		}
+	}
+}
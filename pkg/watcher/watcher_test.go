@@ -0,0 +1,120 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"ronkitay.com/griffin/pkg/language"
+)
+
+type fakeFSWatcher struct {
+	watched []string
+}
+
+func (watcher *fakeFSWatcher) add(dir string) error {
+	watcher.watched = append(watcher.watched, dir)
+	return nil
+}
+
+func (watcher *fakeFSWatcher) events() <-chan fsEvent { return nil }
+func (watcher *fakeFSWatcher) errors() <-chan error   { return nil }
+func (watcher *fakeFSWatcher) close() error           { return nil }
+
+func newTestWatcher(roots ...string) (*Watcher, *fakeFSWatcher) {
+	fs := &fakeFSWatcher{}
+	detector, _ := language.NewDetector(nil, language.Priority{})
+	watcher := &Watcher{fs: fs, detector: detector, repoDirs: map[string]struct{}{}, changedRepos: map[string]struct{}{}}
+	for _, root := range roots {
+		watcher.watchTree(root)
+	}
+	return watcher, fs
+}
+
+func TestWatchTree(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "group", "repo")
+	os.MkdirAll(filepath.Join(repo, ".git", "objects"), 0755)
+	os.MkdirAll(filepath.Join(repo, "service", "node_modules", "lib"), 0755)
+
+	watcher, fs := newTestWatcher(root)
+
+	expected := []string{root, filepath.Join(root, "group"), repo, filepath.Join(repo, "service")}
+	if len(fs.watched) != len(expected) {
+		t.Fatalf("Expected watches on %v, got %v", expected, fs.watched)
+	}
+	for i := range expected {
+		if fs.watched[i] != expected[i] {
+			t.Errorf("Expected watches on %v, got %v", expected, fs.watched)
+		}
+	}
+	if _, found := watcher.repoDirs[repo]; !found {
+		t.Errorf("Expected %s to be known as a repo", repo)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	os.MkdirAll(filepath.Join(repo, ".git"), 0755)
+
+	watcher, fs := newTestWatcher(root)
+
+	// Marker file inside a repo only affects the project index
+	if !watcher.handle(fsEvent{path: filepath.Join(repo, "api", "go.mod")}) {
+		t.Errorf("Expected a marker file to require an update")
+	}
+	if watcher.repoIndexStale {
+		t.Errorf("Did not expect a marker file to invalidate the repo index")
+	}
+	if _, found := watcher.changedRepos[repo]; !found {
+		t.Errorf("Expected %s to be marked as changed", repo)
+	}
+
+	// Other files are ignored
+	if watcher.handle(fsEvent{path: filepath.Join(repo, "main.go")}) {
+		t.Errorf("Did not expect a source file to require an update")
+	}
+
+	// Dependency directories are ignored
+	if watcher.handle(fsEvent{path: filepath.Join(repo, "node_modules"), isDir: true}) {
+		t.Errorf("Did not expect node_modules to require an update")
+	}
+
+	// A new clone is a new repo
+	clone := filepath.Join(root, "clone")
+	os.MkdirAll(filepath.Join(clone, ".git"), 0755)
+	watcher.handle(fsEvent{path: clone, isDir: true})
+	watcher.handle(fsEvent{path: filepath.Join(clone, ".git"), isDir: true})
+	if !watcher.repoIndexStale {
+		t.Errorf("Expected a new clone to invalidate the repo index")
+	}
+	if _, found := watcher.repoDirs[clone]; !found {
+		t.Errorf("Expected %s to be known as a repo", clone)
+	}
+
+	// A directory named *.git is only an archive when it is a bare repository
+	checkout := filepath.Join(root, "website.git")
+	os.MkdirAll(filepath.Join(checkout, ".git"), 0755)
+	watcher.repoIndexStale = false
+	watcher.handle(fsEvent{path: checkout, isDir: true})
+	if _, found := watcher.repoDirs[checkout]; !found {
+		t.Errorf("Expected %s to be watched as a repo", checkout)
+	}
+	bare := filepath.Join(root, "mirror.git")
+	os.MkdirAll(filepath.Join(bare, "objects"), 0755)
+	os.WriteFile(filepath.Join(bare, "HEAD"), []byte("ref: refs/heads/main\n"), 0644)
+	watcher.repoIndexStale = false
+	watcher.handle(fsEvent{path: bare, isDir: true})
+	if !watcher.repoIndexStale || slices.Contains(fs.watched, bare) {
+		t.Errorf("Expected a bare repository to invalidate the repo index without being watched")
+	}
+
+	// A removed repo is forgotten
+	os.RemoveAll(repo)
+	watcher.handle(fsEvent{path: repo, isDir: true})
+	if _, found := watcher.repoDirs[repo]; found {
+		t.Errorf("Expected %s to be forgotten", repo)
+	}
+}